@@ -17,7 +17,10 @@ const (
 	DefaultWindow = 10000
 )
 
-var _ Authenticator = (*AuthenticatorImpl)(nil)
+var (
+	_ Authenticator      = (*AuthenticatorImpl)(nil)
+	_ BatchAuthenticator = (*AuthenticatorImpl)(nil)
+)
 
 type AuthHeaders struct {
 	XTimestamp int64  `json:"X-Timestamp" mapstructure:"X-Timestamp"`
@@ -50,6 +53,11 @@ type Authenticator interface {
 	SetWindow(window int)
 }
 
+// BatchAuthenticator signs requests with several instructions
+type BatchAuthenticator interface {
+	AuthenticateBatch(instruction Instruction, bodies []interface{}) (*AuthHeaders, error)
+}
+
 func NewAuthenticator(window int, secretKey, apiKey string) (Authenticator, error) {
 	impl := &AuthenticatorImpl{
 		window: window,
@@ -87,13 +95,31 @@ func (impl *AuthenticatorImpl) Authenticate(instruction Instruction, body interf
 		return nil, fmt.Errorf("auth query err: %v", err)
 	}
 
+	return impl.headers(fmt.Sprintf("instruction=%s&%s", instruction, bodyQuery))
+}
+
+// Signs batch request, each body is prefixed with its own instruction
+func (impl *AuthenticatorImpl) AuthenticateBatch(instruction Instruction, bodies []interface{}) (*AuthHeaders, error) {
+	parts := make([]string, 0, len(bodies))
+
+	for _, body := range bodies {
+		bodyQuery, err := createQuery(body)
+		if err != nil {
+			return nil, fmt.Errorf("auth query err: %v", err)
+		}
+
+		parts = append(parts, fmt.Sprintf("instruction=%s&%s", instruction, bodyQuery))
+	}
+
+	return impl.headers(strings.Join(parts, "&"))
+}
+
+func (impl *AuthenticatorImpl) headers(instructions string) (*AuthHeaders, error) {
 	ts := time.Now().UTC().UnixMilli()
 
-	query := fmt.Sprintf("instruction=%s&%s&timestamp=%d&window=%d", instruction, bodyQuery, ts, impl.window)
+	query := fmt.Sprintf("%s&timestamp=%d&window=%d", instructions, ts, impl.window)
 	query = strings.ReplaceAll(query, "&&", "&")
 
-	// fmt.Println(query, body, bodyQuery)
-
 	signature, err := impl.sign([]byte(query))
 	if err != nil {
 		return nil, fmt.Errorf("auth signature err: %v", err)
@@ -164,13 +190,18 @@ func structToQuery(v interface{}) (string, error) {
 		field := val.Field(i)
 		fieldType := typ.Field(i)
 
-		jsonTag := fieldType.Tag.Get("json")
+		name, opts, _ := strings.Cut(fieldType.Tag.Get("json"), ",")
+
+		if name == "" || name == "-" {
+			continue
+		}
 
-		if jsonTag == "" || jsonTag == "-" {
+		// skip empty optional fields, they are not sent in body either
+		if strings.Contains(opts, "omitempty") && field.IsZero() {
 			continue
 		}
 
-		values.Add(jsonTag, fmt.Sprintf("%v", field.Interface()))
+		values.Add(name, fmt.Sprintf("%v", field.Interface()))
 	}
 
 	// automaticaly sorted by key
@@ -0,0 +1,303 @@
+package client
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+
+	"github.com/leenzstra/backpack-go/internal/keccak"
+)
+
+var ErrInvalidAddress = errors.New("invalid address")
+
+// AddressValidator returns error wrapping ErrInvalidAddress for malformed address
+type AddressValidator func(address string) error
+
+var (
+	addressMu         sync.RWMutex
+	addressValidators = map[Blockchain]AddressValidator{
+		TypeBitcoin:  ValidateBitcoinAddress,
+		TypeEthereum: ValidateEVMAddress,
+		TypePolygon:  ValidateEVMAddress,
+		TypeSolana:   ValidateSolanaAddress,
+	}
+)
+
+// RegisterAddressValidator sets validator of blockchain, nil removes it
+func RegisterAddressValidator(blockchain Blockchain, validator AddressValidator) {
+	addressMu.Lock()
+	defer addressMu.Unlock()
+
+	if validator == nil {
+		delete(addressValidators, blockchain)
+		return
+	}
+
+	addressValidators[blockchain] = validator
+}
+
+// HasAddressValidator reports if blockchain addresses can be validated
+func (b Blockchain) HasAddressValidator() bool {
+	addressMu.RLock()
+	defer addressMu.RUnlock()
+
+	_, ok := addressValidators[b]
+	return ok
+}
+
+// ValidateAddress checks address with registered validator,
+// blockchains without validator accept any address
+func (b Blockchain) ValidateAddress(address string) error {
+	addressMu.RLock()
+	validator, ok := addressValidators[b]
+	addressMu.RUnlock()
+
+	if !ok {
+		return nil
+	}
+
+	if err := validator(address); err != nil {
+		return fmt.Errorf("%s address %q: %w", b, address, err)
+	}
+
+	return nil
+}
+
+// ValidateBitcoinAddress accepts mainnet P2PKH, P2SH, bech32 and bech32m addresses
+func ValidateBitcoinAddress(address string) error {
+	if strings.HasPrefix(strings.ToLower(address), "bc1") {
+		return validateSegwit(address)
+	}
+
+	decoded, err := base58Decode(address)
+	if err != nil {
+		return err
+	}
+
+	if len(decoded) != 25 {
+		return fmt.Errorf("%w: payload length %d", ErrInvalidAddress, len(decoded))
+	}
+
+	first := sha256.Sum256(decoded[:21])
+	second := sha256.Sum256(first[:])
+
+	if !bytes.Equal(second[:4], decoded[21:]) {
+		return fmt.Errorf("%w: checksum mismatch", ErrInvalidAddress)
+	}
+
+	// P2PKH or P2SH version
+	if decoded[0] != 0x00 && decoded[0] != 0x05 {
+		return fmt.Errorf("%w: unknown version %#x", ErrInvalidAddress, decoded[0])
+	}
+
+	return nil
+}
+
+// ValidateEVMAddress accepts 0x prefixed hex address, mixed case must match EIP-55 checksum
+func ValidateEVMAddress(address string) error {
+	if len(address) != 42 || !strings.HasPrefix(address, "0x") {
+		return fmt.Errorf("%w: must be 0x and 40 hex digits", ErrInvalidAddress)
+	}
+
+	body := address[2:]
+	if _, err := hex.DecodeString(body); err != nil {
+		return fmt.Errorf("%w: not hex", ErrInvalidAddress)
+	}
+
+	// single case addresses carry no checksum
+	if body == strings.ToLower(body) || body == strings.ToUpper(body) {
+		return nil
+	}
+
+	hash := keccak.Sum256([]byte(strings.ToLower(body)))
+
+	for i, c := range body {
+		if c >= '0' && c <= '9' {
+			continue
+		}
+
+		nibble := hash[i/2] >> 4
+		if i%2 == 1 {
+			nibble = hash[i/2] & 0x0f
+		}
+
+		upper := c >= 'A' && c <= 'F'
+		if upper != (nibble >= 8) {
+			return fmt.Errorf("%w: checksum mismatch", ErrInvalidAddress)
+		}
+	}
+
+	return nil
+}
+
+// ValidateSolanaAddress accepts base58 encoded 32 byte public key
+func ValidateSolanaAddress(address string) error {
+	decoded, err := base58Decode(address)
+	if err != nil {
+		return err
+	}
+
+	if len(decoded) != 32 {
+		return fmt.Errorf("%w: key length %d", ErrInvalidAddress, len(decoded))
+	}
+
+	return nil
+}
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+func base58Decode(s string) ([]byte, error) {
+	if s == "" {
+		return nil, fmt.Errorf("%w: empty", ErrInvalidAddress)
+	}
+
+	value := new(big.Int)
+	radix := big.NewInt(58)
+
+	for _, c := range s {
+		i := strings.IndexRune(base58Alphabet, c)
+		if i < 0 {
+			return nil, fmt.Errorf("%w: invalid base58 character %q", ErrInvalidAddress, c)
+		}
+
+		value.Mul(value, radix)
+		value.Add(value, big.NewInt(int64(i)))
+	}
+
+	// leading ones are zero bytes
+	zeros := 0
+	for zeros < len(s) && s[zeros] == '1' {
+		zeros++
+	}
+
+	return append(make([]byte, zeros), value.Bytes()...), nil
+}
+
+const (
+	bech32Charset  = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+	bech32Const    = 1
+	bech32mConst   = 0x2bc830a3
+	bitcoinHRP     = "bc"
+	maxBech32Chars = 90
+)
+
+// validateSegwit checks BIP-173 and BIP-350 witness address
+func validateSegwit(address string) error {
+	if len(address) > maxBech32Chars {
+		return fmt.Errorf("%w: too long", ErrInvalidAddress)
+	}
+
+	if address != strings.ToLower(address) && address != strings.ToUpper(address) {
+		return fmt.Errorf("%w: mixed case", ErrInvalidAddress)
+	}
+
+	address = strings.ToLower(address)
+
+	sep := strings.LastIndexByte(address, '1')
+	if sep < 1 || sep+7 > len(address) {
+		return fmt.Errorf("%w: invalid separator", ErrInvalidAddress)
+	}
+
+	hrp := address[:sep]
+	if hrp != bitcoinHRP {
+		return fmt.Errorf("%w: unknown prefix %q", ErrInvalidAddress, hrp)
+	}
+
+	data := make([]byte, 0, len(address)-sep-1)
+	for _, c := range address[sep+1:] {
+		i := strings.IndexRune(bech32Charset, c)
+		if i < 0 {
+			return fmt.Errorf("%w: invalid bech32 character %q", ErrInvalidAddress, c)
+		}
+
+		data = append(data, byte(i))
+	}
+
+	version := data[0]
+	if version > 16 {
+		return fmt.Errorf("%w: unknown witness version %d", ErrInvalidAddress, version)
+	}
+
+	want := uint32(bech32Const)
+	if version > 0 {
+		want = bech32mConst
+	}
+
+	if bech32Polymod(append(hrpExpand(hrp), data...)) != want {
+		return fmt.Errorf("%w: checksum mismatch", ErrInvalidAddress)
+	}
+
+	program, err := convertBits(data[1:len(data)-6], 5, 8)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case len(program) < 2 || len(program) > 40:
+		return fmt.Errorf("%w: witness program length %d", ErrInvalidAddress, len(program))
+	case version == 0 && len(program) != 20 && len(program) != 32:
+		return fmt.Errorf("%w: witness v0 program length %d", ErrInvalidAddress, len(program))
+	}
+
+	return nil
+}
+
+func bech32Polymod(values []byte) uint32 {
+	generator := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+
+	chk := uint32(1)
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+
+		for i := 0; i < 5; i++ {
+			if (top>>i)&1 == 1 {
+				chk ^= generator[i]
+			}
+		}
+	}
+
+	return chk
+}
+
+func hrpExpand(hrp string) []byte {
+	expanded := make([]byte, 0, len(hrp)*2+1)
+	for _, c := range hrp {
+		expanded = append(expanded, byte(c>>5))
+	}
+
+	expanded = append(expanded, 0)
+
+	for _, c := range hrp {
+		expanded = append(expanded, byte(c&31))
+	}
+
+	return expanded
+}
+
+// convertBits regroups 5 bit words into bytes, padding must be zero
+func convertBits(data []byte, from, to uint) ([]byte, error) {
+	acc, n := uint32(0), uint(0)
+	out := make([]byte, 0, len(data)*int(from)/int(to))
+
+	for _, v := range data {
+		acc = acc<<from | uint32(v)
+		n += from
+
+		for n >= to {
+			n -= to
+			out = append(out, byte(acc>>n)&(1<<to-1))
+		}
+	}
+
+	if n >= from || (acc<<(to-n))&(1<<to-1) != 0 {
+		return nil, fmt.Errorf("%w: invalid padding", ErrInvalidAddress)
+	}
+
+	return out, nil
+}
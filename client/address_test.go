@@ -0,0 +1,80 @@
+package client
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/leenzstra/backpack-go/internal/keccak"
+)
+
+func TestKeccak256(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"", "c5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470"},
+		{"abc", "4e03657aea45a94fc7d47ba826c8d667c0d1e6e33a64a036ec44f58fa12d6c45"},
+	}
+
+	for _, tt := range tests {
+		sum := keccak.Sum256([]byte(tt.input))
+		if got := hex.EncodeToString(sum[:]); got != tt.want {
+			t.Errorf("keccak256(%q) = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestValidateAddress(t *testing.T) {
+	tests := []struct {
+		blockchain Blockchain
+		address    string
+		valid      bool
+	}{
+		// EIP-55
+		{TypeEthereum, "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed", true},
+		{TypeEthereum, "0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359", true},
+		{TypeEthereum, "0xdbF03B407c01E7cD3CBea99509d93f8DDDC8C6FB", true},
+		{TypeEthereum, "0xD1220A0cf47c7B9Be7A2E6BA89F429762e7b9aDb", true},
+		{TypePolygon, "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed", true},
+		{TypeEthereum, "0x5aaeb6053F3E94C9b9A09f33669435E7Ef1BeAed", false},
+		{TypeEthereum, "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAe", false},
+
+		// base58check
+		{TypeBitcoin, "1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa", true},
+		{TypeBitcoin, "3J98t1WpEZ73CNmQviecrnyiWrnqRhWNLy", true},
+		{TypeBitcoin, "1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNb", false},
+
+		// BIP-173
+		{TypeBitcoin, "BC1QW508D6QEJXTDG4Y5R3ZARVARY0C5XW7KV8F3T4", true},
+		{TypeBitcoin, "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t5", false},
+		{TypeBitcoin, "bc1QW508D6QEJXTDG4Y5R3ZARVARY0C5XW7KV8F3T4", false},
+		{TypeBitcoin, "bc1rw5uspcuh", false},
+
+		// BIP-350
+		{TypeBitcoin, "bc1pw508d6qejxtdg4y5r3zarvary0c5xw7kw508d6qejxtdg4y5r3zarvary0c5xw7kt5nd6y", true},
+		{TypeBitcoin, "BC1SW50QGDZ25J", true},
+		{TypeBitcoin, "bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0", true},
+		{TypeBitcoin, "bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqh2y7hd", false},
+		{TypeBitcoin, "BC130XLXVLHEMJA6C4DQV22UAPCTQUPFHLXM9H8Z3K2E72Q4K9HCZ7VQ7ZWS8R", false},
+
+		// base58 public key
+		{TypeSolana, "11111111111111111111111111111111", true},
+		{TypeSolana, "So11111111111111111111111111111111111111112", true},
+		{TypeSolana, "So1111111111111111111111111111111111111111", false},
+		{TypeSolana, "0OIl", false},
+	}
+
+	for _, tt := range tests {
+		err := tt.blockchain.ValidateAddress(tt.address)
+
+		switch {
+		case tt.valid && err != nil:
+			t.Errorf("%s %s: unexpected err: %v", tt.blockchain, tt.address, err)
+		case !tt.valid && err == nil:
+			t.Errorf("%s %s: expected err", tt.blockchain, tt.address)
+		case !tt.valid && !errors.Is(err, ErrInvalidAddress):
+			t.Errorf("%s %s: err %v does not wrap ErrInvalidAddress", tt.blockchain, tt.address, err)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/leenzstra/backpack-go/auth"
+)
+
+const DefaultBatchConcurrency = 8
+
+var ErrBatchUnsupported = errors.New("batch requests are not supported")
+
+// BatchOrders is implemented by Orders which can submit and cancel several orders at once
+type BatchOrders interface {
+	ExecuteOrders(payloads []ExecuteOrderPayload) ([]BatchResult, error)
+	CancelOrdersByID(symbol string, orderIds []string) ([]BatchResult, error)
+}
+
+// BatchResult is outcome of one order in batch request.
+// Index points to position in request slice
+type BatchResult struct {
+	Index   int
+	OrderID string
+	Order   Order
+	Err     error
+}
+
+func (r BatchResult) OK() bool {
+	return r.Err == nil
+}
+
+// Failed returns indexes of failed orders to retry them one by one
+func Failed(results []BatchResult) []int {
+	failed := make([]int, 0)
+
+	for _, r := range results {
+		if r.Err != nil {
+			failed = append(failed, r.Index)
+		}
+	}
+
+	return failed
+}
+
+// ExecuteOrders implements BatchOrders.
+//
+// All orders are signed and sent in one request, result is reported per order.
+// Authenticator must implement auth.BatchAuthenticator
+func (impl *OrdersImpl) ExecuteOrders(payloads []ExecuteOrderPayload) ([]BatchResult, error) {
+	signer, ok := impl.Authenticator.(auth.BatchAuthenticator)
+	if !ok {
+		return nil, fmt.Errorf("%w: authenticator can not sign batch", ErrBatchUnsupported)
+	}
+
+	results := make([]BatchResult, len(payloads))
+	valid := make([]ExecuteOrderPayload, 0, len(payloads))
+	index := make([]int, 0, len(payloads))
+
+	for i := range payloads {
+		results[i].Index = i
+
+		payload := payloads[i]
+
+		if impl.validator != nil {
+			if err := impl.validator.Apply(&payload); err != nil {
+				results[i].Err = err
+				continue
+			}
+		}
+
+		valid = append(valid, payload)
+		index = append(index, i)
+	}
+
+	if len(valid) == 0 {
+		return results, nil
+	}
+
+	bodies := make([]interface{}, len(valid))
+	for i := range valid {
+		bodies[i] = valid[i]
+	}
+
+	headers, err := signer.AuthenticateBatch(auth.OrderExecute, bodies)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := impl.Client().R().SetHeaders(headers.Map()).
+		SetBody(valid).Post("/api/v1/orders")
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, extractError(resp)
+	}
+
+	raw := make([]json.RawMessage, 0)
+	if err := json.Unmarshal(resp.Body(), &raw); err != nil {
+		return nil, fmt.Errorf("decode batch err: %v", err)
+	}
+
+	if len(raw) != len(valid) {
+		return nil, fmt.Errorf("batch response has %d results for %d orders", len(raw), len(valid))
+	}
+
+	for i, r := range raw {
+		result := &results[index[i]]
+
+		apiErr := struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		}{}
+
+		if err := json.Unmarshal(r, &apiErr); err == nil && apiErr.Code != "" {
+			result.Err = fmt.Errorf("%s: %s", apiErr.Code, apiErr.Message)
+			continue
+		}
+
+		result.Order, result.Err = decodeOrder(r)
+		if result.Order != nil {
+			result.OrderID = result.Order.Base().ID
+		}
+	}
+
+	return results, nil
+}
+
+// CancelOrdersByID implements BatchOrders.
+//
+// Exchange has no batch cancel by ids, orders are cancelled concurrently up to Concurrency
+func (impl *OrdersImpl) CancelOrdersByID(symbol string, orderIds []string) ([]BatchResult, error) {
+	results := make([]BatchResult, len(orderIds))
+
+	concurrency := impl.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultBatchConcurrency
+	}
+
+	sem := make(chan struct{}, concurrency)
+	wg := sync.WaitGroup{}
+
+	for i, id := range orderIds {
+		sem <- struct{}{}
+		wg.Add(1)
+
+		go func(i int, id string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			order, err := impl.CancelOrder(CancelOrderPayload{OrderID: id, Symbol: symbol})
+
+			results[i] = BatchResult{Index: i, OrderID: id, Order: order, Err: err}
+		}(i, id)
+	}
+
+	wg.Wait()
+
+	return results, nil
+}
@@ -0,0 +1,244 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+var ErrInvalidOrder = errors.New("invalid order")
+
+type OrderType string
+
+const (
+	OrderTypeLimit  OrderType = "Limit"
+	OrderTypeMarket OrderType = "Market"
+)
+
+type Side string
+
+const (
+	SideBid Side = "Bid"
+	SideAsk Side = "Ask"
+)
+
+type TimeInForce string
+
+const (
+	TimeInForceGTC TimeInForce = "GTC"
+	TimeInForceIOC TimeInForce = "IOC"
+	TimeInForceFOK TimeInForce = "FOK"
+)
+
+type SelfTradePrevention string
+
+const (
+	STPRejectTaker SelfTradePrevention = "RejectTaker"
+	STPRejectMaker SelfTradePrevention = "RejectMaker"
+	STPRejectBoth  SelfTradePrevention = "RejectBoth"
+	STPAllow       SelfTradePrevention = "Allow"
+)
+
+// OrderBuilder assembles ExecuteOrderPayload for one order type.
+// Errors are collected and returned by Build
+type OrderBuilder struct {
+	payload ExecuteOrderPayload
+	errs    []error
+}
+
+// Limit order resting at price
+func NewLimitOrder(symbol string, side Side, price, quantity string) *OrderBuilder {
+	return &OrderBuilder{
+		payload: ExecuteOrderPayload{
+			OrderType: string(OrderTypeLimit),
+			Symbol:    symbol,
+			Side:      string(side),
+			Price:     price,
+			Quantity:  quantity,
+		},
+	}
+}
+
+// Market order, size must be set with Quantity or QuoteQuantity
+func NewMarketOrder(symbol string, side Side) *OrderBuilder {
+	return &OrderBuilder{
+		payload: ExecuteOrderPayload{
+			OrderType: string(OrderTypeMarket),
+			Symbol:    symbol,
+			Side:      string(side),
+		},
+	}
+}
+
+// Market order triggered at triggerPrice, use Limit to make it stop-limit
+func NewStopOrder(symbol string, side Side, triggerPrice, quantity string) *OrderBuilder {
+	return &OrderBuilder{
+		payload: ExecuteOrderPayload{
+			OrderType:    string(OrderTypeMarket),
+			Symbol:       symbol,
+			Side:         string(side),
+			Quantity:     quantity,
+			TriggerPrice: triggerPrice,
+		},
+	}
+}
+
+// Turns stop order into stop-limit order with price
+func (b *OrderBuilder) Limit(price string) *OrderBuilder {
+	if b.payload.TriggerPrice == "" {
+		b.errs = append(b.errs, fmt.Errorf("limit price can be added only to stop order"))
+	}
+
+	b.payload.OrderType = string(OrderTypeLimit)
+	b.payload.Price = price
+
+	return b
+}
+
+func (b *OrderBuilder) Quantity(quantity string) *OrderBuilder {
+	b.payload.Quantity = quantity
+	return b
+}
+
+func (b *OrderBuilder) QuoteQuantity(quantity string) *OrderBuilder {
+	b.payload.QuoteQuantity = quantity
+	return b
+}
+
+func (b *OrderBuilder) TriggerPrice(price string) *OrderBuilder {
+	b.payload.TriggerPrice = price
+	return b
+}
+
+func (b *OrderBuilder) ClientID(id uint32) *OrderBuilder {
+	b.payload.ClientID = id
+	return b
+}
+
+func (b *OrderBuilder) PostOnly() *OrderBuilder {
+	b.payload.PostOnly = true
+	return b
+}
+
+func (b *OrderBuilder) TimeInForce(tif TimeInForce) *OrderBuilder {
+	if b.payload.TimeInForce != "" && b.payload.TimeInForce != string(tif) {
+		b.errs = append(b.errs, fmt.Errorf("time in force already set to %s", b.payload.TimeInForce))
+	}
+
+	b.payload.TimeInForce = string(tif)
+	return b
+}
+
+func (b *OrderBuilder) GTC() *OrderBuilder {
+	return b.TimeInForce(TimeInForceGTC)
+}
+
+func (b *OrderBuilder) IOC() *OrderBuilder {
+	return b.TimeInForce(TimeInForceIOC)
+}
+
+func (b *OrderBuilder) FOK() *OrderBuilder {
+	return b.TimeInForce(TimeInForceFOK)
+}
+
+func (b *OrderBuilder) SelfTradePrevention(stp SelfTradePrevention) *OrderBuilder {
+	b.payload.SelfTradePrevention = string(stp)
+	return b
+}
+
+// Build validates collected fields and returns payload for ExecuteOrder
+func (b *OrderBuilder) Build() (ExecuteOrderPayload, error) {
+	errs := append([]error{}, b.errs...)
+
+	if err := b.payload.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if len(errs) > 0 {
+		return ExecuteOrderPayload{}, fmt.Errorf("%w: %w", ErrInvalidOrder, errors.Join(errs...))
+	}
+
+	return b.payload, nil
+}
+
+// Validate checks required and mutually exclusive fields of payload
+func (p ExecuteOrderPayload) Validate() error {
+	errs := make([]error, 0)
+
+	if p.Symbol == "" {
+		errs = append(errs, fmt.Errorf("symbol is required"))
+	}
+
+	switch Side(p.Side) {
+	case SideBid, SideAsk:
+	default:
+		errs = append(errs, fmt.Errorf("unknown side %q", p.Side))
+	}
+
+	switch TimeInForce(p.TimeInForce) {
+	case "", TimeInForceGTC, TimeInForceIOC, TimeInForceFOK:
+	default:
+		errs = append(errs, fmt.Errorf("unknown time in force %q", p.TimeInForce))
+	}
+
+	switch OrderType(p.OrderType) {
+	case OrderTypeLimit:
+		errs = append(errs, requirePositive("price", p.Price))
+		errs = append(errs, requirePositive("quantity", p.Quantity))
+
+		if p.QuoteQuantity != "" {
+			errs = append(errs, fmt.Errorf("quote quantity is not allowed for limit order"))
+		}
+
+		if p.PostOnly && p.TimeInForce != "" && TimeInForce(p.TimeInForce) != TimeInForceGTC {
+			errs = append(errs, fmt.Errorf("post only order can not be %s", p.TimeInForce))
+		}
+
+	case OrderTypeMarket:
+		if p.Price != "" {
+			errs = append(errs, fmt.Errorf("price is not allowed for market order"))
+		}
+
+		if p.PostOnly {
+			errs = append(errs, fmt.Errorf("market order can not be post only"))
+		}
+
+		switch {
+		case p.Quantity != "" && p.QuoteQuantity != "":
+			errs = append(errs, fmt.Errorf("quantity and quote quantity are mutually exclusive"))
+		case p.Quantity != "":
+			errs = append(errs, requirePositive("quantity", p.Quantity))
+		case p.QuoteQuantity != "":
+			errs = append(errs, requirePositive("quote quantity", p.QuoteQuantity))
+		default:
+			errs = append(errs, fmt.Errorf("quantity or quote quantity is required"))
+		}
+
+	default:
+		errs = append(errs, fmt.Errorf("unknown order type %q", p.OrderType))
+	}
+
+	if p.TriggerPrice != "" {
+		errs = append(errs, requirePositive("trigger price", p.TriggerPrice))
+	}
+
+	return errors.Join(errs...)
+}
+
+func requirePositive(name, value string) error {
+	if value == "" {
+		return fmt.Errorf("%s is required", name)
+	}
+
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		return fmt.Errorf("%s %q is not a number", name, value)
+	}
+
+	if v <= 0 {
+		return fmt.Errorf("%s must be positive, got %s", name, value)
+	}
+
+	return nil
+}
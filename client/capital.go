@@ -1,13 +1,18 @@
 package client
 
 import (
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/leenzstra/backpack-go/auth"
 )
 
 var _ Capital = (*CapitalImpl)(nil)
 
+// ErrNotSent wraps failures of request which did not reach exchange
+var ErrNotSent = errors.New("request not sent")
+
 type Blockchain string
 
 const (
@@ -25,9 +30,21 @@ type Capital interface {
 	RequestWithdrawal(payload *WithdrawalRequest) (*Withdrawal, error)
 }
 
+// WithdrawalValidator checks withdrawal before it is signed and sent
+type WithdrawalValidator interface {
+	ValidateWithdrawal(payload WithdrawalRequest) error
+}
+
 type CapitalImpl struct {
 	Base
 	auth.Authenticator
+
+	validator WithdrawalValidator
+}
+
+// SetWithdrawalValidator enables checks in RequestWithdrawal, nil disables
+func (impl *CapitalImpl) SetWithdrawalValidator(validator WithdrawalValidator) {
+	impl.validator = validator
 }
 
 // Balances implements Capital.
@@ -78,7 +95,7 @@ func (impl *CapitalImpl) DepositAddress(blockchain Blockchain) (*DepositAddress,
 }
 
 // Deposits implements Capital.
-// Limit 0-1000
+// Limit 0-MaxPageLimit
 // Offset 0-N
 func (impl *CapitalImpl) Deposits(limit int64, offset int64) ([]Deposit, error) {
 	deposits := make([]Deposit, 0)
@@ -106,13 +123,23 @@ func (impl *CapitalImpl) Deposits(limit int64, offset int64) ([]Deposit, error)
 	return deposits, nil
 }
 
-// RequestWithdrawal implements Capital.
+// RequestWithdrawal implements Capital. Failures before request is sent wrap ErrNotSent
 func (impl *CapitalImpl) RequestWithdrawal(payload *WithdrawalRequest) (*Withdrawal, error) {
 	withdrawal := &Withdrawal{}
 
+	if err := Blockchain(payload.Blockchain).ValidateAddress(payload.Address); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrNotSent, err)
+	}
+
+	if impl.validator != nil {
+		if err := impl.validator.ValidateWithdrawal(*payload); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrNotSent, err)
+		}
+	}
+
 	headers, err := impl.Authenticate(auth.Withdraw, payload)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%w: %w", ErrNotSent, err)
 	}
 
 	resp, err := impl.Client().R().SetHeaders(headers.Map()).
@@ -205,3 +232,13 @@ type WithdrawalRequest struct {
 	Symbol         string `json:"symbol"`
 	TwoFactorToken string `json:"twoFactorToken"`
 }
+
+// Time parses deposit creation timestamp, it is UTC without zone
+func (d Deposit) Time() (time.Time, error) {
+	return time.Parse(timestampLayout, d.CreatedAt)
+}
+
+// Time parses withdrawal creation timestamp, it is UTC without zone
+func (w Withdrawal) Time() (time.Time, error) {
+	return time.Parse(timestampLayout, w.CreatedAt)
+}
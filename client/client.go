@@ -20,9 +20,9 @@ func NewBackpackClient(endpoint string, authenticator auth.Authenticator) Backpa
 	impl.SystemImpl = SystemImpl{impl.APIBase}
 	impl.TradesImpl = TradesImpl{impl.APIBase}
 
-	impl.CapitalImpl = CapitalImpl{impl.APIBase, impl.Authenticator}
-	impl.HistoryImpl = HistoryImpl{impl.APIBase, impl.Authenticator}
-	impl.OrdersImpl = OrdersImpl{impl.APIBase, impl.Authenticator}
+	impl.Capital = &CapitalImpl{Base: impl.APIBase, Authenticator: impl.Authenticator}
+	impl.History = &HistoryImpl{impl.APIBase, impl.Authenticator}
+	impl.Orders = &OrdersImpl{Base: impl.APIBase, Authenticator: impl.Authenticator}
 
 	return impl
 }
@@ -53,8 +53,17 @@ type BackpackClient interface {
 	Capital
 	History
 	Orders
+	BatchOrders
 
 	auth.Authenticator
+
+	SetOrderValidator(validator *OrderValidator)
+	SetWithdrawalValidator(validator WithdrawalValidator)
+
+	// Replace private API implementations, e.g. with paper trading
+	SetOrders(orders Orders)
+	SetCapital(capital Capital)
+	SetHistory(history History)
 }
 
 type BackpackClientImpl struct {
@@ -64,9 +73,55 @@ type BackpackClientImpl struct {
 	SystemImpl
 	TradesImpl
 
-	CapitalImpl
-	HistoryImpl
-	OrdersImpl
+	Capital
+	History
+	Orders
 
 	auth.Authenticator
 }
+
+// SetOrderValidator is applied if current Orders supports it
+func (impl *BackpackClientImpl) SetOrderValidator(validator *OrderValidator) {
+	if o, ok := impl.Orders.(interface{ SetOrderValidator(*OrderValidator) }); ok {
+		o.SetOrderValidator(validator)
+	}
+}
+
+// SetWithdrawalValidator is applied if current Capital supports it
+func (impl *BackpackClientImpl) SetWithdrawalValidator(validator WithdrawalValidator) {
+	if c, ok := impl.Capital.(interface{ SetWithdrawalValidator(WithdrawalValidator) }); ok {
+		c.SetWithdrawalValidator(validator)
+	}
+}
+
+// ExecuteOrders implements BatchOrders if current Orders supports it
+func (impl *BackpackClientImpl) ExecuteOrders(payloads []ExecuteOrderPayload) ([]BatchResult, error) {
+	batch, ok := impl.Orders.(BatchOrders)
+	if !ok {
+		return nil, ErrBatchUnsupported
+	}
+
+	return batch.ExecuteOrders(payloads)
+}
+
+// CancelOrdersByID implements BatchOrders if current Orders supports it
+func (impl *BackpackClientImpl) CancelOrdersByID(symbol string, orderIds []string) ([]BatchResult, error) {
+	batch, ok := impl.Orders.(BatchOrders)
+	if !ok {
+		return nil, ErrBatchUnsupported
+	}
+
+	return batch.CancelOrdersByID(symbol, orderIds)
+}
+
+func (impl *BackpackClientImpl) SetOrders(orders Orders) {
+	impl.Orders = orders
+}
+
+func (impl *BackpackClientImpl) SetCapital(capital Capital) {
+	impl.Capital = capital
+}
+
+func (impl *BackpackClientImpl) SetHistory(history History) {
+	impl.History = history
+}
@@ -6,6 +6,21 @@ import (
 	"github.com/go-resty/resty/v2"
 )
 
+// APIError is returned when exchange responds with error status
+type APIError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("status %d, error %s", e.StatusCode, e.Body)
+}
+
+// Request was rejected and not executed. 5xx errors may be returned after execution
+func (e *APIError) Rejected() bool {
+	return e.StatusCode < 500
+}
+
 func extractError(req *resty.Response) error {
-	return fmt.Errorf("status %d, error %s", req.StatusCode(), req.String())
+	return &APIError{StatusCode: req.StatusCode(), Body: req.String()}
 }
@@ -0,0 +1,294 @@
+package client
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+)
+
+type Rounding int
+
+const (
+	RoundDown Rounding = iota
+	RoundUp
+	RoundNearest
+)
+
+// FilterError describes which market filter rejected the order
+type FilterError struct {
+	Symbol string
+	Field  string
+	Value  string
+	Limit  string
+	Reason string
+}
+
+func (e *FilterError) Error() string {
+	if e.Limit == "" {
+		return fmt.Sprintf("%s %s %s: %s", e.Symbol, e.Field, e.Value, e.Reason)
+	}
+
+	return fmt.Sprintf("%s %s %s: %s %s", e.Symbol, e.Field, e.Value, e.Reason, e.Limit)
+}
+
+func (e *FilterError) Unwrap() error {
+	return ErrInvalidOrder
+}
+
+// OrderValidator snaps orders to market filters and checks bounds before submit.
+// Markets are loaded on first use and cached until Refresh
+type OrderValidator struct {
+	PriceRounding    Rounding
+	QuantityRounding Rounding
+
+	markets Markets
+
+	mu          sync.RWMutex
+	filters     map[string]Market
+	minNotional map[string]string
+}
+
+func NewOrderValidator(markets Markets) *OrderValidator {
+	return &OrderValidator{
+		PriceRounding:    RoundNearest,
+		QuantityRounding: RoundDown,
+		markets:          markets,
+		minNotional:      make(map[string]string),
+	}
+}
+
+// Exchange does not report minimum notional, so it is configured per symbol.
+// Empty symbol sets default for all markets
+func (v *OrderValidator) SetMinNotional(symbol string, notional string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.minNotional[symbol] = notional
+}
+
+// Refresh reloads market filters
+func (v *OrderValidator) Refresh() error {
+	markets, err := v.markets.Markets()
+	if err != nil {
+		return err
+	}
+
+	filters := make(map[string]Market, len(markets))
+	for _, m := range markets {
+		filters[m.Symbol] = m
+	}
+
+	v.mu.Lock()
+	v.filters = filters
+	v.mu.Unlock()
+
+	return nil
+}
+
+func (v *OrderValidator) Market(symbol string) (*Market, error) {
+	v.mu.RLock()
+	loaded := v.filters != nil
+	v.mu.RUnlock()
+
+	if !loaded {
+		if err := v.Refresh(); err != nil {
+			return nil, fmt.Errorf("load markets err: %v", err)
+		}
+	}
+
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	market, ok := v.filters[symbol]
+	if !ok {
+		return nil, &FilterError{Symbol: symbol, Field: "symbol", Value: symbol, Reason: "unknown market"}
+	}
+
+	return &market, nil
+}
+
+func (v *OrderValidator) RoundPrice(symbol, price string) (string, error) {
+	market, err := v.Market(symbol)
+	if err != nil {
+		return "", err
+	}
+
+	return RoundToStep(price, market.Filters.Price.TickSize, v.PriceRounding)
+}
+
+func (v *OrderValidator) RoundQuantity(symbol, quantity string) (string, error) {
+	market, err := v.Market(symbol)
+	if err != nil {
+		return "", err
+	}
+
+	return RoundToStep(quantity, market.Filters.Quantity.StepSize, v.QuantityRounding)
+}
+
+// Apply snaps price, trigger price, quantity and quote quantity of payload in place
+// and checks them against market bounds
+func (v *OrderValidator) Apply(payload *ExecuteOrderPayload) error {
+	market, err := v.Market(payload.Symbol)
+	if err != nil {
+		return err
+	}
+
+	price := market.Filters.Price
+	quantity := market.Filters.Quantity
+
+	if payload.Price != "" {
+		if payload.Price, err = snap(payload.Symbol, "price", payload.Price, price.TickSize, v.PriceRounding, price.MinPrice, price.MaxPrice); err != nil {
+			return err
+		}
+	}
+
+	if payload.TriggerPrice != "" {
+		if payload.TriggerPrice, err = snap(payload.Symbol, "trigger price", payload.TriggerPrice, price.TickSize, v.PriceRounding, price.MinPrice, price.MaxPrice); err != nil {
+			return err
+		}
+	}
+
+	if payload.Quantity != "" {
+		if payload.Quantity, err = snap(payload.Symbol, "quantity", payload.Quantity, quantity.StepSize, v.QuantityRounding, quantity.MinQuantity, quantity.MaxQuantity); err != nil {
+			return err
+		}
+	}
+
+	// quote amount has price precision, market has no quote bounds
+	if payload.QuoteQuantity != "" {
+		if payload.QuoteQuantity, err = snap(payload.Symbol, "quote quantity", payload.QuoteQuantity, price.TickSize, v.QuantityRounding, "", ""); err != nil {
+			return err
+		}
+	}
+
+	return v.checkNotional(payload)
+}
+
+func (v *OrderValidator) checkNotional(payload *ExecuteOrderPayload) error {
+	v.mu.RLock()
+	minimum, ok := v.minNotional[payload.Symbol]
+	if !ok {
+		minimum = v.minNotional[""]
+	}
+	v.mu.RUnlock()
+
+	if minimum == "" {
+		return nil
+	}
+
+	var notional *big.Rat
+
+	switch {
+	case payload.QuoteQuantity != "":
+		notional, _ = new(big.Rat).SetString(payload.QuoteQuantity)
+	case payload.Price != "" && payload.Quantity != "":
+		p, _ := new(big.Rat).SetString(payload.Price)
+		q, _ := new(big.Rat).SetString(payload.Quantity)
+		if p != nil && q != nil {
+			notional = new(big.Rat).Mul(p, q)
+		}
+	}
+
+	// market order by base quantity, price is unknown before fill
+	if notional == nil {
+		return nil
+	}
+
+	limit, ok := new(big.Rat).SetString(minimum)
+	if !ok {
+		return fmt.Errorf("invalid min notional %q", minimum)
+	}
+
+	if notional.Cmp(limit) < 0 {
+		return &FilterError{
+			Symbol: payload.Symbol,
+			Field:  "notional",
+			Value:  notional.FloatString(8),
+			Limit:  minimum,
+			Reason: "below minimum",
+		}
+	}
+
+	return nil
+}
+
+// snap checks sign of value before rounding it to step, then checks rounded value against bounds
+func snap(symbol, field, value, step string, mode Rounding, min, max string) (string, error) {
+	v, ok := new(big.Rat).SetString(value)
+	if !ok {
+		return "", &FilterError{Symbol: symbol, Field: field, Value: value, Reason: "not a number"}
+	}
+
+	if v.Sign() <= 0 {
+		return "", &FilterError{Symbol: symbol, Field: field, Value: value, Reason: "must be positive"}
+	}
+
+	rounded, err := RoundToStep(value, step, mode)
+	if err != nil {
+		return "", err
+	}
+
+	return rounded, checkBounds(symbol, field, rounded, min, max)
+}
+
+func checkBounds(symbol, field, value, min, max string) error {
+	v, ok := new(big.Rat).SetString(value)
+	if !ok {
+		return &FilterError{Symbol: symbol, Field: field, Value: value, Reason: "not a number"}
+	}
+
+	if v.Sign() <= 0 {
+		return &FilterError{Symbol: symbol, Field: field, Value: value, Reason: "rounds to zero"}
+	}
+
+	if l, ok := new(big.Rat).SetString(min); ok && v.Cmp(l) < 0 {
+		return &FilterError{Symbol: symbol, Field: field, Value: value, Limit: min, Reason: "below minimum"}
+	}
+
+	if l, ok := new(big.Rat).SetString(max); ok && l.Sign() > 0 && v.Cmp(l) > 0 {
+		return &FilterError{Symbol: symbol, Field: field, Value: value, Limit: max, Reason: "above maximum"}
+	}
+
+	return nil
+}
+
+// RoundToStep snaps decimal value to multiple of step.
+// Result has as many decimals as step
+func RoundToStep(value, step string, mode Rounding) (string, error) {
+	v, ok := new(big.Rat).SetString(value)
+	if !ok {
+		return "", fmt.Errorf("invalid decimal %q", value)
+	}
+
+	s, ok := new(big.Rat).SetString(step)
+	if !ok || s.Sign() <= 0 {
+		return value, nil
+	}
+
+	q := new(big.Rat).Quo(v, s)
+	n := new(big.Int).Quo(q.Num(), q.Denom())
+	rem := new(big.Rat).Sub(q, new(big.Rat).SetInt(n))
+
+	switch mode {
+	case RoundUp:
+		if rem.Sign() > 0 {
+			n.Add(n, big.NewInt(1))
+		}
+	case RoundNearest:
+		if rem.Mul(rem, big.NewRat(2, 1)).Cmp(big.NewRat(1, 1)) >= 0 {
+			n.Add(n, big.NewInt(1))
+		}
+	}
+
+	return new(big.Rat).Mul(new(big.Rat).SetInt(n), s).FloatString(decimals(step)), nil
+}
+
+func decimals(step string) int {
+	_, frac, ok := strings.Cut(step, ".")
+	if !ok {
+		return 0
+	}
+
+	return len(strings.TrimRight(frac, "0"))
+}
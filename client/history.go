@@ -2,12 +2,16 @@ package client
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/leenzstra/backpack-go/auth"
 )
 
 var _ History = (*HistoryImpl)(nil)
 
+// Layout of timestamps in history responses
+const timestampLayout = "2006-01-02T15:04:05"
+
 type History interface {
 	OrderHistory(orderId, symbol string, offset, limit int64) ([]Order, error)
 	FillHistory(orderId, symbol string, from, to, offset, limit int64) ([]Fill, error)
@@ -18,19 +22,25 @@ type HistoryImpl struct {
 	auth.Authenticator
 }
 
-// FillHistory implements History.
+// FillHistory implements History. From and to are unix ms, zero is not limited
 func (impl *HistoryImpl) FillHistory(orderId string, symbol string, from int64, to int64, offset int64, limit int64) ([]Fill, error) {
 	history := make([]Fill, 0)
 
 	query := map[string]string{
 		"orderId": orderId,
 		"symbol":  symbol,
-		"from":    fmt.Sprint(from),
-		"to":      fmt.Sprint(to),
 		"offset":  fmt.Sprint(offset),
 		"limit":   fmt.Sprint(limit),
 	}
 
+	// zero is not limited
+	if from != 0 {
+		query["from"] = fmt.Sprint(from)
+	}
+	if to != 0 {
+		query["to"] = fmt.Sprint(to)
+	}
+
 	headers, err := impl.Authenticate(auth.FillHistoryQueryAll, query)
 	if err != nil {
 		return nil, err
@@ -50,8 +60,6 @@ func (impl *HistoryImpl) FillHistory(orderId string, symbol string, from int64,
 
 // OrderHistory implements History.
 func (impl *HistoryImpl) OrderHistory(orderId string, symbol string, offset int64, limit int64) ([]Order, error) {
-	history := make([]Order, 0)
-
 	query := map[string]string{
 		"orderId": orderId,
 		"symbol":  symbol,
@@ -64,7 +72,7 @@ func (impl *HistoryImpl) OrderHistory(orderId string, symbol string, offset int6
 		return nil, err
 	}
 
-	resp, err := impl.Client().R().SetHeaders(headers.Map()).SetQueryParams(query).SetResult(&history).Get("/api/v1/history/orders")
+	resp, err := impl.Client().R().SetHeaders(headers.Map()).SetQueryParams(query).Get("/api/v1/history/orders")
 	if err != nil {
 		return nil, err
 	}
@@ -73,22 +81,7 @@ func (impl *HistoryImpl) OrderHistory(orderId string, symbol string, offset int6
 		return nil, extractError(resp)
 	}
 
-	return history, nil
-}
-
-type Order struct {
-	ID                  string `json:"id"`
-	OrderType           string `json:"orderType"`
-	Symbol              string `json:"symbol"`
-	Side                string `json:"side"`
-	Price               string `json:"price"`
-	TriggerPrice        string `json:"triggerPrice"`
-	Quantity            string `json:"quantity"`
-	QuoteQuantity       string `json:"quoteQuantity"`
-	TimeInForce         string `json:"timeInForce"`
-	SelfTradePrevention string `json:"selfTradePrevention"`
-	PostOnly            bool   `json:"postOnly"`
-	Status              string `json:"status"`
+	return decodeOrders(resp.Body())
 }
 
 type Fill struct {
@@ -103,3 +96,8 @@ type Fill struct {
 	IsMaker   bool   `json:"isMaker"`
 	Timestamp string `json:"timestamp"`
 }
+
+// Time parses fill timestamp, it is UTC without zone
+func (f Fill) Time() (time.Time, error) {
+	return time.Parse(timestampLayout, f.Timestamp)
+}
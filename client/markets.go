@@ -2,6 +2,7 @@ package client
 
 import (
 	"fmt"
+	"strconv"
 	"time"
 )
 
@@ -153,16 +154,19 @@ func (impl *MarketsImpl) Tickers() ([]Ticker, error) {
 }
 
 type Asset struct {
-	Symbol string `json:"symbol"`
-	Tokens []struct {
-		Blockchain        string `json:"blockchain"`
-		DepositEnabled    bool   `json:"depositEnabled"`
-		MinimumDeposit    string `json:"minimumDeposit"`
-		WithdrawEnabled   bool   `json:"withdrawEnabled"`
-		MinimumWithdrawal string `json:"minimumWithdrawal"`
-		MaximumWithdrawal string `json:"maximumWithdrawal"`
-		WithdrawalFee     string `json:"withdrawalFee"`
-	} `json:"tokens"`
+	Symbol string  `json:"symbol"`
+	Tokens []Token `json:"tokens"`
+}
+
+// Token is asset on one blockchain
+type Token struct {
+	Blockchain        string `json:"blockchain"`
+	DepositEnabled    bool   `json:"depositEnabled"`
+	MinimumDeposit    string `json:"minimumDeposit"`
+	WithdrawEnabled   bool   `json:"withdrawEnabled"`
+	MinimumWithdrawal string `json:"minimumWithdrawal"`
+	MaximumWithdrawal string `json:"maximumWithdrawal"`
+	WithdrawalFee     string `json:"withdrawalFee"`
 }
 
 type Market struct {
@@ -207,6 +211,31 @@ type Depth struct {
 	LastUpdateID string     `json:"lastUpdateId"`
 }
 
+// Best returns highest bid and lowest ask, zero if side is empty
+func (d *Depth) Best() (bid, ask float64) {
+	for _, level := range d.Bids {
+		if len(level) == 0 {
+			continue
+		}
+
+		if p, err := strconv.ParseFloat(level[0], 64); err == nil && p > bid {
+			bid = p
+		}
+	}
+
+	for _, level := range d.Asks {
+		if len(level) == 0 {
+			continue
+		}
+
+		if p, err := strconv.ParseFloat(level[0], 64); err == nil && (ask == 0 || p < ask) {
+			ask = p
+		}
+	}
+
+	return bid, ask
+}
+
 type KLinePoint struct {
 	Start  string `json:"start"`
 	Open   string `json:"open"`
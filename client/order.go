@@ -1,30 +1,43 @@
 package client
 
 import (
+	"encoding/json"
 	"fmt"
 
 	"github.com/leenzstra/backpack-go/auth"
 )
 
-var _ Orders = (*OrdersImpl)(nil)
+var (
+	_ Orders      = (*OrdersImpl)(nil)
+	_ BatchOrders = (*OrdersImpl)(nil)
+)
 
 type Orders interface {
-	OpenOrder(clientId uint32, orderId string, symbol string) (*BaseOrder, error)
-	ExecuteOrder(payload ExecuteOrderPayload) (*BaseOrder, error)
-	CancelOrder(payload CancelOrderPayload) (*BaseOrder, error)
+	OpenOrder(clientId uint32, orderId string, symbol string) (Order, error)
+	ExecuteOrder(payload ExecuteOrderPayload) (Order, error)
+	CancelOrder(payload CancelOrderPayload) (Order, error)
 
-	OpenOrders(symbol string) ([]BaseOrder, error)
-	CancelOrders(payload CancelOrderPayload) ([]BaseOrder, error)
+	OpenOrders(symbol string) ([]Order, error)
+	CancelOrders(payload CancelOrderPayload) ([]Order, error)
 }
 
 type OrdersImpl struct {
 	Base
 	auth.Authenticator
+
+	// Parallel cancels in CancelOrdersByID, zero is DefaultBatchConcurrency
+	Concurrency int
+
+	validator *OrderValidator
+}
+
+// SetOrderValidator enables rounding and filter checks in ExecuteOrder, nil disables
+func (impl *OrdersImpl) SetOrderValidator(validator *OrderValidator) {
+	impl.validator = validator
 }
 
 // CancelOrder implements Orders.
-func (impl *OrdersImpl) CancelOrder(payload CancelOrderPayload) (*BaseOrder, error) {
-	order := &BaseOrder{}
+func (impl *OrdersImpl) CancelOrder(payload CancelOrderPayload) (Order, error) {
 
 	headers, err := impl.Authenticate(auth.OrderCancel, payload)
 	if err != nil {
@@ -32,7 +45,7 @@ func (impl *OrdersImpl) CancelOrder(payload CancelOrderPayload) (*BaseOrder, err
 	}
 
 	resp, err := impl.Client().R().SetHeaders(headers.Map()).
-		SetBody(payload).SetResult(order).Delete("/api/v1/order")
+		SetBody(payload).Delete("/api/v1/order")
 	if err != nil {
 		return nil, err
 	}
@@ -41,15 +54,13 @@ func (impl *OrdersImpl) CancelOrder(payload CancelOrderPayload) (*BaseOrder, err
 		return nil, extractError(resp)
 	}
 
-	return order, nil
+	return decodeOrder(resp.Body())
 }
 
 // CancelOrders implements Orders.
 //
 // Fill only symbol
-func (impl *OrdersImpl) CancelOrders(payload CancelOrderPayload) ([]BaseOrder, error) {
-	orders := make([]BaseOrder, 0)
-
+func (impl *OrdersImpl) CancelOrders(payload CancelOrderPayload) ([]Order, error) {
 	required := map[string]string{
 		"symbol": payload.Symbol,
 	}
@@ -60,7 +71,7 @@ func (impl *OrdersImpl) CancelOrders(payload CancelOrderPayload) ([]BaseOrder, e
 	}
 
 	resp, err := impl.Client().R().SetHeaders(headers.Map()).
-		SetBody(required).SetResult(&orders).Delete("/api/v1/orders")
+		SetBody(required).Delete("/api/v1/orders")
 	if err != nil {
 		return nil, err
 	}
@@ -69,12 +80,16 @@ func (impl *OrdersImpl) CancelOrders(payload CancelOrderPayload) ([]BaseOrder, e
 		return nil, extractError(resp)
 	}
 
-	return orders, nil
+	return decodeOrders(resp.Body())
 }
 
 // ExecuteOrder implements Orders.
-func (impl *OrdersImpl) ExecuteOrder(payload ExecuteOrderPayload) (*BaseOrder, error) {
-	order := &BaseOrder{}
+func (impl *OrdersImpl) ExecuteOrder(payload ExecuteOrderPayload) (Order, error) {
+	if impl.validator != nil {
+		if err := impl.validator.Apply(&payload); err != nil {
+			return nil, err
+		}
+	}
 
 	headers, err := impl.Authenticate(auth.OrderExecute, payload)
 	if err != nil {
@@ -82,7 +97,7 @@ func (impl *OrdersImpl) ExecuteOrder(payload ExecuteOrderPayload) (*BaseOrder, e
 	}
 
 	resp, err := impl.Client().R().SetHeaders(headers.Map()).
-		SetBody(payload).SetResult(order).Post("/api/v1/order")
+		SetBody(payload).Post("/api/v1/order")
 	if err != nil {
 		return nil, err
 	}
@@ -91,17 +106,23 @@ func (impl *OrdersImpl) ExecuteOrder(payload ExecuteOrderPayload) (*BaseOrder, e
 		return nil, extractError(resp)
 	}
 
-	return order, nil
+	return decodeOrder(resp.Body())
 }
 
 // OpenOrder implements Orders.
-func (impl *OrdersImpl) OpenOrder(clientId uint32, orderId string, symbol string) (*BaseOrder, error) {
-	order := &BaseOrder{}
+func (impl *OrdersImpl) OpenOrder(clientId uint32, orderId string, symbol string) (Order, error) {
 
 	query := map[string]string{
-		"clientId": fmt.Sprint(clientId),
-		"orderId":  orderId,
-		"symbol":   symbol,
+		"symbol": symbol,
+	}
+
+	// order is looked up by any of ids
+	if clientId != 0 {
+		query["clientId"] = fmt.Sprint(clientId)
+	}
+
+	if orderId != "" {
+		query["orderId"] = orderId
 	}
 
 	headers, err := impl.Authenticate(auth.OrderQuery, query)
@@ -110,7 +131,7 @@ func (impl *OrdersImpl) OpenOrder(clientId uint32, orderId string, symbol string
 	}
 
 	resp, err := impl.Client().R().SetHeaders(headers.Map()).
-		SetQueryParams(query).SetResult(order).Get("/api/v1/order")
+		SetQueryParams(query).Get("/api/v1/order")
 	if err != nil {
 		return nil, err
 	}
@@ -119,13 +140,11 @@ func (impl *OrdersImpl) OpenOrder(clientId uint32, orderId string, symbol string
 		return nil, extractError(resp)
 	}
 
-	return order, nil
+	return decodeOrder(resp.Body())
 }
 
 // OpenOrders implements Orders.
-func (impl *OrdersImpl) OpenOrders(symbol string) ([]BaseOrder, error) {
-	orders := make([]BaseOrder, 0)
-
+func (impl *OrdersImpl) OpenOrders(symbol string) ([]Order, error) {
 	query := map[string]string{
 		"symbol": symbol,
 	}
@@ -136,7 +155,7 @@ func (impl *OrdersImpl) OpenOrders(symbol string) ([]BaseOrder, error) {
 	}
 
 	resp, err := impl.Client().R().SetHeaders(headers.Map()).
-		SetQueryParams(query).SetResult(&orders).Get("/api/v1/orders")
+		SetQueryParams(query).Get("/api/v1/orders")
 	if err != nil {
 		return nil, err
 	}
@@ -145,13 +164,76 @@ func (impl *OrdersImpl) OpenOrders(symbol string) ([]BaseOrder, error) {
 		return nil, extractError(resp)
 	}
 
+	return decodeOrders(resp.Body())
+}
+
+const (
+	StatusNew             = "New"
+	StatusPartiallyFilled = "PartiallyFilled"
+	StatusFilled          = "Filled"
+	StatusCancelled       = "Cancelled"
+	StatusExpired         = "Expired"
+	StatusTriggerPending  = "TriggerPending"
+)
+
+// Order is implemented by *MarketOrder and *LimitOrder, type switch to get
+// order type specific fields. Unknown order types are returned as *BaseOrder
+type Order interface {
+	Base() *BaseOrder
+}
+
+func decodeOrder(data []byte) (Order, error) {
+	head := struct {
+		OrderType string `json:"orderType"`
+	}{}
+
+	if err := json.Unmarshal(data, &head); err != nil {
+		return nil, fmt.Errorf("decode order err: %v", err)
+	}
+
+	var order Order
+
+	switch OrderType(head.OrderType) {
+	case OrderTypeLimit:
+		order = &LimitOrder{}
+	case OrderTypeMarket:
+		order = &MarketOrder{}
+	default:
+		order = &BaseOrder{}
+	}
+
+	if err := json.Unmarshal(data, order); err != nil {
+		return nil, fmt.Errorf("decode order err: %v", err)
+	}
+
+	return order, nil
+}
+
+func decodeOrders(data []byte) ([]Order, error) {
+	raw := make([]json.RawMessage, 0)
+
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("decode orders err: %v", err)
+	}
+
+	orders := make([]Order, 0, len(raw))
+
+	for _, r := range raw {
+		order, err := decodeOrder(r)
+		if err != nil {
+			return nil, err
+		}
+
+		orders = append(orders, order)
+	}
+
 	return orders, nil
 }
 
 type BaseOrder struct {
 	OrderType             string `json:"orderType"`
 	ID                    string `json:"id"`
-	ClientID              int    `json:"clientId"`
+	ClientID              uint32 `json:"clientId"`
 	Symbol                string `json:"symbol"`
 	Side                  string `json:"side"`
 	Quantity              string `json:"quantity"`
@@ -164,6 +246,20 @@ type BaseOrder struct {
 	CreatedAt             int    `json:"createdAt"`
 }
 
+func (o *BaseOrder) Base() *BaseOrder {
+	return o
+}
+
+// Order will not change anymore
+func (o *BaseOrder) IsTerminal() bool {
+	switch o.Status {
+	case StatusFilled, StatusCancelled, StatusExpired:
+		return true
+	}
+
+	return false
+}
+
 type MarketOrder struct {
 	BaseOrder
 	QuoteQuantity string `json:"quoteQuantity"`
@@ -176,21 +272,21 @@ type LimitOrder struct {
 }
 
 type ExecuteOrderPayload struct {
-	ClientID            int    `json:"clientId"`
+	ClientID            uint32 `json:"clientId,omitempty"`
 	OrderType           string `json:"orderType"`
-	PostOnly            bool   `json:"postOnly"`
-	Price               string `json:"price"`
-	Quantity            string `json:"quantity"`
-	QuoteQuantity       string `json:"quoteQuantity"`
-	SelfTradePrevention string `json:"selfTradePrevention"`
+	PostOnly            bool   `json:"postOnly,omitempty"`
+	Price               string `json:"price,omitempty"`
+	Quantity            string `json:"quantity,omitempty"`
+	QuoteQuantity       string `json:"quoteQuantity,omitempty"`
+	SelfTradePrevention string `json:"selfTradePrevention,omitempty"`
 	Side                string `json:"side"`
 	Symbol              string `json:"symbol"`
-	TimeInForce         string `json:"timeInForce"`
-	TriggerPrice        string `json:"triggerPrice"`
+	TimeInForce         string `json:"timeInForce,omitempty"`
+	TriggerPrice        string `json:"triggerPrice,omitempty"`
 }
 
 type CancelOrderPayload struct {
-	ClientID int    `json:"clientId"`
-	OrderID  string `json:"orderId"`
+	ClientID uint32 `json:"clientId,omitempty"`
+	OrderID  string `json:"orderId,omitempty"`
 	Symbol   string `json:"symbol"`
 }
@@ -0,0 +1,206 @@
+package client
+
+import (
+	"context"
+	"time"
+)
+
+// MaxPageLimit is largest limit accepted by offset/limit endpoints
+const MaxPageLimit = 1000
+
+// PageFunc loads page of items at offset
+type PageFunc[T any] func(offset, limit int64) ([]T, error)
+
+type page[T any] struct {
+	items []T
+	err   error
+}
+
+// Pager walks offset/limit endpoint until short page is returned.
+//
+//	p := client.NewPager(client.DepositsPage(capital))
+//	for p.Next(ctx) {
+//		deposit := p.Item()
+//	}
+//	err := p.Err()
+type Pager[T any] struct {
+	// Page size, zero is MaxPageLimit
+	Limit int64
+	// Stop after this many items, zero is unlimited
+	MaxItems int
+	// Stop before first item for which it returns true, e.g. item older than range
+	Stop func(item T) bool
+	// Load next page while current one is consumed
+	Prefetch bool
+
+	fetch  PageFunc[T]
+	offset int64
+	buf    []T
+	item   T
+	count  int
+	done   bool
+	err    error
+	next   chan page[T]
+}
+
+func NewPager[T any](fetch PageFunc[T]) *Pager[T] {
+	return &Pager[T]{fetch: fetch}
+}
+
+// Next advances to next item, false when pages are exhausted, stop condition is met or on error
+func (p *Pager[T]) Next(ctx context.Context) bool {
+	for len(p.buf) == 0 {
+		if p.done || p.err != nil {
+			return false
+		}
+
+		if err := ctx.Err(); err != nil {
+			p.err = err
+			return false
+		}
+
+		items, err := p.page(ctx)
+		if err != nil {
+			p.err = err
+			return false
+		}
+
+		p.buf = items
+
+		if int64(len(items)) < p.limit() {
+			p.done = true
+		} else if p.Prefetch {
+			p.prefetch()
+		}
+	}
+
+	item := p.buf[0]
+	p.buf = p.buf[1:]
+
+	if p.Stop != nil && p.Stop(item) {
+		p.finish()
+		return false
+	}
+
+	p.item = item
+	p.count++
+
+	if p.MaxItems > 0 && p.count >= p.MaxItems {
+		p.finish()
+	}
+
+	return true
+}
+
+// Item returns current item
+func (p *Pager[T]) Item() T {
+	return p.item
+}
+
+// Err returns first error, context error included
+func (p *Pager[T]) Err() error {
+	return p.err
+}
+
+func (p *Pager[T]) limit() int64 {
+	if p.Limit <= 0 || p.Limit > MaxPageLimit {
+		return MaxPageLimit
+	}
+
+	return p.Limit
+}
+
+func (p *Pager[T]) page(ctx context.Context) ([]T, error) {
+	if p.next != nil {
+		select {
+		case r := <-p.next:
+			p.next = nil
+			return r.items, r.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	items, err := p.fetch(p.offset, p.limit())
+	p.offset += p.limit()
+
+	return items, err
+}
+
+func (p *Pager[T]) prefetch() {
+	// buffered, abandoned prefetch does not block goroutine
+	ch := make(chan page[T], 1)
+	offset, limit, fetch := p.offset, p.limit(), p.fetch
+
+	go func() {
+		items, err := fetch(offset, limit)
+		ch <- page[T]{items, err}
+	}()
+
+	p.offset += limit
+	p.next = ch
+}
+
+func (p *Pager[T]) finish() {
+	p.done = true
+	p.buf = nil
+}
+
+// Collect loads all items of pager
+func Collect[T any](ctx context.Context, p *Pager[T]) ([]T, error) {
+	items := make([]T, 0)
+
+	for p.Next(ctx) {
+		items = append(items, p.Item())
+	}
+
+	return items, p.Err()
+}
+
+// StopBefore stops newest first pager at first item older than from.
+// Items with unparsable time do not stop pager
+func StopBefore[T any](from time.Time, timeOf func(item T) (time.Time, error)) func(item T) bool {
+	return func(item T) bool {
+		t, err := timeOf(item)
+		return err == nil && t.Before(from)
+	}
+}
+
+func DepositsPage(capital Capital) PageFunc[Deposit] {
+	return func(offset, limit int64) ([]Deposit, error) {
+		return capital.Deposits(limit, offset)
+	}
+}
+
+func WithdrawalsPage(capital Capital) PageFunc[Withdrawal] {
+	return func(offset, limit int64) ([]Withdrawal, error) {
+		return capital.Withdrawals(limit, offset)
+	}
+}
+
+func OrderHistoryPage(history History, orderId, symbol string) PageFunc[Order] {
+	return func(offset, limit int64) ([]Order, error) {
+		return history.OrderHistory(orderId, symbol, offset, limit)
+	}
+}
+
+// FillHistoryPage filters fills by time on server, zero times are not limited
+func FillHistoryPage(history History, orderId, symbol string, from, to time.Time) PageFunc[Fill] {
+	var fromMs, toMs int64
+	if !from.IsZero() {
+		fromMs = from.UnixMilli()
+	}
+	if !to.IsZero() {
+		toMs = to.UnixMilli()
+	}
+
+	return func(offset, limit int64) ([]Fill, error) {
+		return history.FillHistory(orderId, symbol, fromMs, toMs, offset, limit)
+	}
+}
+
+func HistoricalTradesPage(trades Trades, symbol string) PageFunc[Trade] {
+	return func(offset, limit int64) ([]Trade, error) {
+		return trades.HistoricalTrades(symbol, limit, offset)
+	}
+}
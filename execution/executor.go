@@ -0,0 +1,477 @@
+package execution
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/leenzstra/backpack-go/client"
+	"github.com/leenzstra/backpack-go/internal/num"
+	"github.com/leenzstra/backpack-go/trading"
+)
+
+var ErrCancelled = errors.New("execution cancelled")
+
+// API is part of client used by executors
+type API interface {
+	client.Orders
+	client.History
+	client.Markets
+}
+
+type ChildStyle int
+
+const (
+	// Passive post only limit at best price on own side, unfilled rest is cancelled at next slice
+	ChildLimit ChildStyle = iota
+	// Limit IOC crossing spread up to MaxSlippageBps
+	ChildIOC
+)
+
+type State int
+
+const (
+	Running State = iota
+	Paused
+	Cancelled
+	Done
+)
+
+func (s State) String() string {
+	switch s {
+	case Running:
+		return "running"
+	case Paused:
+		return "paused"
+	case Cancelled:
+		return "cancelled"
+	case Done:
+		return "done"
+	}
+
+	return fmt.Sprintf("state(%d)", int(s))
+}
+
+type Params struct {
+	Symbol   string
+	Side     client.Side
+	Quantity float64
+	Duration time.Duration
+	Slices   int
+	// 0-1, jitter of slice size and timing
+	Randomize float64
+	Style     ChildStyle
+	// IOC price limit against best opposite price
+	MaxSlippageBps float64
+}
+
+func (p Params) validate() error {
+	switch {
+	case p.Symbol == "":
+		return fmt.Errorf("symbol is required")
+	case p.Side != client.SideBid && p.Side != client.SideAsk:
+		return fmt.Errorf("unknown side %q", p.Side)
+	case p.Quantity <= 0:
+		return fmt.Errorf("quantity must be positive")
+	case p.Duration <= 0:
+		return fmt.Errorf("duration must be positive")
+	case p.Slices <= 0:
+		return fmt.Errorf("slices must be positive")
+	case p.Randomize < 0 || p.Randomize > 1:
+		return fmt.Errorf("randomize must be 0-1")
+	}
+
+	return nil
+}
+
+type Progress struct {
+	State         State
+	Target        float64
+	Executed      float64
+	ExecutedQuote float64
+	SlicesDone    int
+	Slices        int
+	// Mid price when execution started
+	ArrivalPrice float64
+}
+
+func (p Progress) AveragePrice() float64 {
+	if p.Executed == 0 {
+		return 0
+	}
+
+	return p.ExecutedQuote / p.Executed
+}
+
+// Slippage of average price against arrival price in bps, positive is worse
+func (p Progress) SlippageBps(side client.Side) float64 {
+	if p.ArrivalPrice == 0 || p.Executed == 0 {
+		return 0
+	}
+
+	diff := (p.AveragePrice() - p.ArrivalPrice) / p.ArrivalPrice * 10000
+	if side == client.SideAsk {
+		return -diff
+	}
+
+	return diff
+}
+
+func (p Progress) Remaining() float64 {
+	return math.Max(p.Target-p.Executed, 0)
+}
+
+// Executor works parent order in slices by schedule of weights and offsets
+type Executor struct {
+	// Called after every slice
+	OnProgress func(p Progress)
+
+	api       API
+	validator *client.OrderValidator
+	params    Params
+	offsets   []time.Duration
+	weights   []float64
+
+	mu       sync.Mutex
+	progress Progress
+	resume   chan struct{}
+	cancel   chan struct{}
+	child    client.Order
+}
+
+func newExecutor(api API, validator *client.OrderValidator, params Params, offsets []time.Duration, weights []float64) *Executor {
+	if validator == nil {
+		validator = client.NewOrderValidator(api)
+	}
+
+	return &Executor{
+		api:       api,
+		validator: validator,
+		params:    params,
+		offsets:   offsets,
+		weights:   weights,
+		progress:  Progress{Target: params.Quantity, Slices: len(weights)},
+		cancel:    make(chan struct{}),
+	}
+}
+
+func (e *Executor) Progress() Progress {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.progress
+}
+
+// Pause stops new slices, resting child order is kept
+func (e *Executor) Pause() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.progress.State == Running {
+		e.progress.State = Paused
+		e.resume = make(chan struct{})
+	}
+}
+
+func (e *Executor) Resume() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.progress.State == Paused {
+		e.progress.State = Running
+		close(e.resume)
+	}
+}
+
+// Cancel stops execution, resting child order is cancelled by Run
+func (e *Executor) Cancel() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.progress.State == Running || e.progress.State == Paused {
+		e.progress.State = Cancelled
+		close(e.cancel)
+	}
+}
+
+// Run executes all slices and returns final progress
+func (e *Executor) Run(ctx context.Context) (Progress, error) {
+	depth, err := e.api.Depth(e.params.Symbol)
+	if err != nil {
+		return e.Progress(), err
+	}
+
+	bid, ask := depth.Best()
+
+	e.mu.Lock()
+	e.progress.ArrivalPrice = (bid + ask) / 2
+	e.mu.Unlock()
+
+	start := time.Now()
+
+	for i := range e.weights {
+		if err := e.waitUntil(ctx, start.Add(e.offsets[i])); err != nil {
+			return e.stop(err)
+		}
+
+		// resting child of previous slice gives way to new one
+		if err := e.closeChild(); err != nil {
+			return e.stop(err)
+		}
+
+		if err := e.slice(i); err != nil {
+			return e.stop(err)
+		}
+	}
+
+	if err := e.waitUntil(ctx, start.Add(e.params.Duration)); err != nil {
+		return e.stop(err)
+	}
+
+	if err := e.closeChild(); err != nil {
+		return e.stop(err)
+	}
+
+	e.mu.Lock()
+	e.progress.State = Done
+	p := e.progress
+	e.mu.Unlock()
+
+	return p, nil
+}
+
+// slice sends child order for remaining share of slice i
+func (e *Executor) slice(i int) error {
+	rest := 0.0
+	for _, w := range e.weights[i:] {
+		rest += w
+	}
+
+	p := e.Progress()
+
+	quantity := p.Remaining()
+	if rest > 0 && i < len(e.weights)-1 {
+		quantity = quantity * e.weights[i] / rest
+	}
+
+	payload, err := e.childPayload(quantity)
+
+	if errors.Is(err, client.ErrInvalidOrder) {
+		// slice below filters, its size moves to next slices
+		e.sliceDone()
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	order, err := e.api.ExecuteOrder(payload)
+	if err != nil {
+		return err
+	}
+
+	if order.Base().IsTerminal() {
+		e.account(order)
+	} else {
+		e.mu.Lock()
+		e.child = order
+		e.mu.Unlock()
+	}
+
+	e.sliceDone()
+
+	return nil
+}
+
+func (e *Executor) childPayload(quantity float64) (client.ExecuteOrderPayload, error) {
+	depth, err := e.api.Depth(e.params.Symbol)
+	if err != nil {
+		return client.ExecuteOrderPayload{}, err
+	}
+
+	bid, ask := depth.Best()
+
+	payload := client.ExecuteOrderPayload{
+		OrderType: string(client.OrderTypeLimit),
+		Quantity:  num.String(quantity),
+		Side:      string(e.params.Side),
+		Symbol:    e.params.Symbol,
+	}
+
+	slip := e.params.MaxSlippageBps / 10000
+
+	switch {
+	case e.params.Style == ChildIOC && e.params.Side == client.SideBid:
+		payload.Price = num.String(ask * (1 + slip))
+		payload.TimeInForce = string(client.TimeInForceIOC)
+	case e.params.Style == ChildIOC:
+		payload.Price = num.String(bid * (1 - slip))
+		payload.TimeInForce = string(client.TimeInForceIOC)
+	case e.params.Side == client.SideBid:
+		payload.Price = num.String(bid)
+		payload.PostOnly = true
+	default:
+		payload.Price = num.String(ask)
+		payload.PostOnly = true
+	}
+
+	// IOC price must not be rounded beyond slippage limit
+	rounding := client.RoundNearest
+	switch {
+	case e.params.Style == ChildIOC && e.params.Side == client.SideBid:
+		rounding = client.RoundDown
+	case e.params.Style == ChildIOC:
+		rounding = client.RoundUp
+	}
+
+	market, err := e.validator.Market(e.params.Symbol)
+	if err != nil {
+		return payload, err
+	}
+
+	if payload.Price, err = client.RoundToStep(payload.Price, market.Filters.Price.TickSize, rounding); err != nil {
+		return payload, err
+	}
+
+	if err := e.validator.Apply(&payload); err != nil {
+		return payload, err
+	}
+
+	return payload, nil
+}
+
+// closeChild cancels resting child and accounts its fills
+func (e *Executor) closeChild() error {
+	e.mu.Lock()
+	child := e.child
+	e.child = nil
+	e.mu.Unlock()
+
+	if child == nil {
+		return nil
+	}
+
+	base := child.Base()
+
+	order, err := e.api.CancelOrder(client.CancelOrderPayload{OrderID: base.ID, Symbol: base.Symbol})
+	if err == nil {
+		e.account(order)
+		return nil
+	}
+
+	// cancel fails for order closed in meantime
+	history, herr := e.api.OrderHistory(base.ID, base.Symbol, 0, 1)
+	if herr != nil {
+		return fmt.Errorf("cancel child %s err: %v, history err: %v", base.ID, err, herr)
+	}
+
+	for _, order := range history {
+		if order.Base().ID != base.ID {
+			continue
+		}
+
+		// history may miss execution fields of any closed order, fills decide
+		quantity, quote, ferr := trading.Executed(e.api, base.ID, base.Symbol)
+		if ferr != nil {
+			return fmt.Errorf("cancel child %s err: %v, fills err: %v", base.ID, err, ferr)
+		}
+
+		order.Base().ExecutedQuantity = num.String(quantity)
+		order.Base().ExecutedQuoteQuantity = num.String(quote)
+
+		e.account(order)
+		return nil
+	}
+
+	return fmt.Errorf("cancel child %s err: %v", base.ID, err)
+}
+
+func (e *Executor) account(order client.Order) {
+	base := order.Base()
+
+	e.mu.Lock()
+	e.progress.Executed += num.Float(base.ExecutedQuantity)
+	e.progress.ExecutedQuote += num.Float(base.ExecutedQuoteQuantity)
+	e.mu.Unlock()
+}
+
+func (e *Executor) sliceDone() {
+	e.mu.Lock()
+	e.progress.SlicesDone++
+	p := e.progress
+	e.mu.Unlock()
+
+	if e.OnProgress != nil {
+		e.OnProgress(p)
+	}
+}
+
+// waitUntil sleeps till t, holding while paused
+func (e *Executor) waitUntil(ctx context.Context, t time.Time) error {
+	for {
+		e.mu.Lock()
+		resume := e.resume
+		paused := e.progress.State == Paused
+		e.mu.Unlock()
+
+		if paused {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-e.cancel:
+				return ErrCancelled
+			case <-resume:
+				continue
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-e.cancel:
+			return ErrCancelled
+		case <-time.After(time.Until(t)):
+		}
+
+		// may be paused while sleeping
+		e.mu.Lock()
+		paused = e.progress.State == Paused
+		e.mu.Unlock()
+
+		if !paused {
+			return nil
+		}
+	}
+}
+
+func (e *Executor) stop(err error) (Progress, error) {
+	cerr := e.closeChild()
+
+	e.mu.Lock()
+	if e.progress.State != Cancelled {
+		e.progress.State = Cancelled
+		close(e.cancel)
+	}
+	p := e.progress
+	e.mu.Unlock()
+
+	if errors.Is(err, ErrCancelled) {
+		err = nil
+	}
+
+	return p, errors.Join(err, cerr)
+}
+
+// jitter returns v shifted randomly by up to ±amount/2 of v
+func jitter(v, amount float64) float64 {
+	if amount == 0 {
+		return v
+	}
+
+	return v * (1 + (rand.Float64()-0.5)*amount)
+}
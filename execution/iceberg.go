@@ -0,0 +1,328 @@
+package execution
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/leenzstra/backpack-go/client"
+	"github.com/leenzstra/backpack-go/internal/num"
+	"github.com/leenzstra/backpack-go/trading"
+)
+
+type IcebergParams struct {
+	Symbol   string
+	Side     client.Side
+	Quantity float64
+	// Fixed price of slices, zero pegs slices to best price on own side
+	Price float64
+	// Pegged price never goes above limit for Bid and below limit for Ask, zero disables
+	PriceLimit      float64
+	DisplayQuantity float64
+	// 0-1, jitter of display size
+	DisplayRandomize float64
+}
+
+func (p IcebergParams) validate() error {
+	switch {
+	case p.Symbol == "":
+		return fmt.Errorf("symbol is required")
+	case p.Side != client.SideBid && p.Side != client.SideAsk:
+		return fmt.Errorf("unknown side %q", p.Side)
+	case p.Quantity <= 0 || p.DisplayQuantity <= 0:
+		return fmt.Errorf("quantity and display quantity must be positive")
+	case p.DisplayQuantity > p.Quantity:
+		return fmt.Errorf("display quantity is above total quantity")
+	case p.DisplayRandomize < 0 || p.DisplayRandomize > 1:
+		return fmt.Errorf("display randomize must be 0-1")
+	}
+
+	return nil
+}
+
+type IcebergProgress struct {
+	State         State
+	Target        float64
+	Executed      float64
+	ExecutedQuote float64
+	// Resting size of current slice
+	Visible float64
+	Slices  int
+}
+
+func (p IcebergProgress) AveragePrice() float64 {
+	if p.Executed == 0 {
+		return 0
+	}
+
+	return p.ExecutedQuote / p.Executed
+}
+
+// Hidden is size not shown on book yet
+func (p IcebergProgress) Hidden() float64 {
+	return math.Max(p.Target-p.Executed-p.Visible, 0)
+}
+
+// Iceberg rests one slice at a time. On each fill slice is cancelled and shown again
+// at display size while hidden size is left. Tracker must be running to deliver slice updates
+type Iceberg struct {
+	// Called after every slice update
+	OnProgress func(p IcebergProgress)
+
+	api       API
+	tracker   *trading.Tracker
+	validator *client.OrderValidator
+	params    IcebergParams
+
+	mu       sync.Mutex
+	progress IcebergProgress
+	cancel   context.CancelFunc
+}
+
+func NewIceberg(api API, tracker *trading.Tracker, validator *client.OrderValidator, params IcebergParams) (*Iceberg, error) {
+	if err := params.validate(); err != nil {
+		return nil, fmt.Errorf("iceberg params err: %v", err)
+	}
+
+	if validator == nil {
+		validator = client.NewOrderValidator(api)
+	}
+
+	return &Iceberg{
+		api:       api,
+		tracker:   tracker,
+		validator: validator,
+		params:    params,
+		progress:  IcebergProgress{Target: params.Quantity},
+	}, nil
+}
+
+func (ice *Iceberg) Progress() IcebergProgress {
+	ice.mu.Lock()
+	defer ice.mu.Unlock()
+
+	return ice.progress
+}
+
+// Cancel stops replenishing, resting slice is cancelled by Run
+func (ice *Iceberg) Cancel() {
+	ice.mu.Lock()
+	defer ice.mu.Unlock()
+
+	if ice.cancel != nil {
+		ice.cancel()
+	}
+
+	if ice.progress.State == Running {
+		ice.progress.State = Cancelled
+	}
+}
+
+// Run rests slices until total quantity is executed
+func (ice *Iceberg) Run(ctx context.Context) (IcebergProgress, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	ice.mu.Lock()
+	ice.cancel = cancel
+	cancelled := ice.progress.State == Cancelled
+	ice.mu.Unlock()
+
+	if cancelled {
+		return ice.Progress(), nil
+	}
+
+	for {
+		p := ice.Progress()
+		if p.Target-p.Executed <= 0 {
+			break
+		}
+
+		payload, err := ice.slicePayload(p.Target - p.Executed)
+		if errors.Is(err, client.ErrInvalidOrder) {
+			// rest is below market minimum, can not be shown
+			break
+		}
+
+		if err != nil {
+			return ice.finish(Cancelled, err)
+		}
+
+		slice, err := ice.tracker.Submit(payload)
+		if err != nil {
+			return ice.finish(Cancelled, err)
+		}
+
+		ice.update(func(p *IcebergProgress) {
+			p.Visible = slice.Remaining()
+			p.Slices++
+		})
+
+		final, err := ice.wait(ctx, slice)
+		ice.tracker.Forget(slice.ID)
+
+		quote, qerr := ice.quote(final)
+
+		ice.update(func(p *IcebergProgress) {
+			p.Executed += final.ExecutedQuantity
+			p.ExecutedQuote += quote
+			p.Visible = 0
+		})
+
+		if err == nil {
+			err = qerr
+		}
+
+		if err != nil {
+			if ctx.Err() != nil && ice.Progress().State == Cancelled {
+				return ice.finish(Cancelled, nil)
+			}
+
+			return ice.finish(Cancelled, err)
+		}
+	}
+
+	return ice.finish(Done, nil)
+}
+
+// wait blocks until slice is closed, slice is cancelled when ctx is done
+func (ice *Iceberg) wait(ctx context.Context, slice trading.TrackedOrder) (trading.TrackedOrder, error) {
+	lastFilled := slice.ExecutedQuantity
+
+	for {
+		o, err := ice.tracker.Wait(ctx, slice.ID, func(o trading.TrackedOrder) bool {
+			return o.IsTerminal() || o.ExecutedQuantity != lastFilled
+		})
+
+		if err == nil && !o.IsTerminal() {
+			lastFilled = o.ExecutedQuantity
+
+			ice.update(func(p *IcebergProgress) {
+				p.Visible = o.Remaining()
+			})
+
+			// hidden size is left, slice is shown again at display size
+			p := ice.Progress()
+			if p.Target-p.Executed-o.ExecutedQuantity > o.Remaining() {
+				// cancel fails if slice is closed meanwhile, tracker reports it
+				if final, cerr := ice.cancelSlice(slice); cerr == nil {
+					return final, nil
+				}
+			}
+
+			continue
+		}
+
+		if err == nil {
+			return o, nil
+		}
+
+		if ctx.Err() == nil {
+			return o, err
+		}
+
+		final, cerr := ice.cancelSlice(slice)
+		if cerr != nil {
+			return o, cerr
+		}
+
+		return final, ctx.Err()
+	}
+}
+
+func (ice *Iceberg) cancelSlice(slice trading.TrackedOrder) (trading.TrackedOrder, error) {
+	order, err := ice.api.CancelOrder(client.CancelOrderPayload{OrderID: slice.ID, Symbol: slice.Symbol})
+	if err != nil {
+		return slice, fmt.Errorf("cancel slice %s err: %v", slice.ID, err)
+	}
+
+	ice.tracker.Update(order)
+	final, _ := ice.tracker.Get(slice.ID)
+
+	return final, nil
+}
+
+// quote returns executed quote of closed slice, from fills if order has none
+func (ice *Iceberg) quote(final trading.TrackedOrder) (float64, error) {
+	if final.ExecutedQuantity <= 0 || final.ExecutedQuoteQuantity > 0 {
+		return final.ExecutedQuoteQuantity, nil
+	}
+
+	quantity, quote, err := trading.Executed(ice.api, final.ID, final.Symbol)
+	if err != nil {
+		return 0, fmt.Errorf("slice %s fills err: %v", final.ID, err)
+	}
+
+	if quantity <= 0 {
+		return 0, fmt.Errorf("slice %s has no fills", final.ID)
+	}
+
+	// fills may lag behind order, their average price is used
+	return quote / quantity * final.ExecutedQuantity, nil
+}
+
+func (ice *Iceberg) slicePayload(remaining float64) (client.ExecuteOrderPayload, error) {
+	display := math.Min(jitter(ice.params.DisplayQuantity, ice.params.DisplayRandomize), remaining)
+
+	price := ice.params.Price
+
+	if price == 0 {
+		depth, err := ice.api.Depth(ice.params.Symbol)
+		if err != nil {
+			return client.ExecuteOrderPayload{}, err
+		}
+
+		bid, ask := depth.Best()
+
+		price = bid
+		if ice.params.Side == client.SideAsk {
+			price = ask
+		}
+
+		if limit := ice.params.PriceLimit; limit != 0 {
+			if ice.params.Side == client.SideBid {
+				price = math.Min(price, limit)
+			} else {
+				price = math.Max(price, limit)
+			}
+		}
+	}
+
+	payload := client.ExecuteOrderPayload{
+		OrderType: string(client.OrderTypeLimit),
+		Price:     num.String(price),
+		Quantity:  num.String(display),
+		Side:      string(ice.params.Side),
+		Symbol:    ice.params.Symbol,
+		PostOnly:  true,
+	}
+
+	if err := ice.validator.Apply(&payload); err != nil {
+		return payload, err
+	}
+
+	return payload, nil
+}
+
+func (ice *Iceberg) update(fn func(p *IcebergProgress)) {
+	ice.mu.Lock()
+	fn(&ice.progress)
+	p := ice.progress
+	ice.mu.Unlock()
+
+	if ice.OnProgress != nil {
+		ice.OnProgress(p)
+	}
+}
+
+func (ice *Iceberg) finish(state State, err error) (IcebergProgress, error) {
+	ice.mu.Lock()
+	defer ice.mu.Unlock()
+
+	ice.progress.State = state
+	ice.cancel = nil
+
+	return ice.progress, err
+}
@@ -0,0 +1,33 @@
+package execution
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/leenzstra/backpack-go/client"
+)
+
+// NewTWAP splits quantity into equal slices spread evenly over duration.
+// Randomize jitters slice sizes and send times to hide the pattern
+func NewTWAP(api API, validator *client.OrderValidator, params Params) (*Executor, error) {
+	if err := params.validate(); err != nil {
+		return nil, fmt.Errorf("twap params err: %v", err)
+	}
+
+	interval := params.Duration / time.Duration(params.Slices)
+
+	offsets := make([]time.Duration, params.Slices)
+	weights := make([]float64, params.Slices)
+
+	for i := range offsets {
+		offsets[i] = time.Duration(i) * interval
+		if i > 0 {
+			offsets[i] += time.Duration((rand.Float64() - 0.5) * params.Randomize * float64(interval))
+		}
+
+		weights[i] = jitter(1, params.Randomize)
+	}
+
+	return newExecutor(api, validator, params, offsets, weights), nil
+}
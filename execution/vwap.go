@@ -0,0 +1,152 @@
+package execution
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/leenzstra/backpack-go/client"
+	"github.com/leenzstra/backpack-go/internal/num"
+)
+
+const DefaultProfileLookback = 7 * 24 * time.Hour
+
+// VolumeProfile is average traded volume by time of day
+type VolumeProfile struct {
+	Bucket  time.Duration
+	Volumes []float64
+}
+
+// Weight of moment t, flat profile gives equal weights
+func (p VolumeProfile) Weight(t time.Time) float64 {
+	if len(p.Volumes) == 0 || p.Bucket <= 0 {
+		return 1
+	}
+
+	day := t.UTC().Sub(t.UTC().Truncate(24 * time.Hour))
+	i := int(day/p.Bucket) % len(p.Volumes)
+
+	return p.Volumes[i]
+}
+
+// BuildVolumeProfile averages KLines volume of last lookback period by time of day.
+// Interval must be shorter than a day
+func BuildVolumeProfile(markets client.Markets, symbol string, interval client.Interval, lookback time.Duration) (VolumeProfile, error) {
+	bucket, err := intervalDuration(interval)
+	if err != nil {
+		return VolumeProfile{}, err
+	}
+
+	if bucket >= 24*time.Hour {
+		return VolumeProfile{}, fmt.Errorf("profile interval %s must be shorter than a day", interval)
+	}
+
+	end := time.Now()
+
+	klines, err := markets.KLines(symbol, interval, end.Add(-lookback), end)
+	if err != nil {
+		return VolumeProfile{}, err
+	}
+
+	n := int(24 * time.Hour / bucket)
+	volumes := make([]float64, n)
+	counts := make([]int, n)
+
+	for _, k := range klines {
+		start, err := parseTime(k.Start)
+		if err != nil {
+			return VolumeProfile{}, fmt.Errorf("kline start %q err: %v", k.Start, err)
+		}
+
+		day := start.UTC().Sub(start.UTC().Truncate(24 * time.Hour))
+		i := int(day/bucket) % n
+
+		volumes[i] += num.Float(k.Volume)
+		counts[i]++
+	}
+
+	for i := range volumes {
+		if counts[i] > 0 {
+			volumes[i] /= float64(counts[i])
+		}
+	}
+
+	return VolumeProfile{Bucket: bucket, Volumes: volumes}, nil
+}
+
+// NewVWAP sizes slices by volume profile at their send times
+func NewVWAP(api API, validator *client.OrderValidator, params Params, profile VolumeProfile) (*Executor, error) {
+	if err := params.validate(); err != nil {
+		return nil, fmt.Errorf("vwap params err: %v", err)
+	}
+
+	interval := params.Duration / time.Duration(params.Slices)
+	start := time.Now()
+
+	offsets := make([]time.Duration, params.Slices)
+	weights := make([]float64, params.Slices)
+
+	total := 0.0
+
+	for i := range offsets {
+		offsets[i] = time.Duration(i) * interval
+		weights[i] = jitter(profile.Weight(start.Add(offsets[i])), params.Randomize)
+		total += weights[i]
+	}
+
+	// no volume in profile for whole window, fall back to twap
+	if total == 0 {
+		for i := range weights {
+			weights[i] = 1
+		}
+	}
+
+	return newExecutor(api, validator, params, offsets, weights), nil
+}
+
+func intervalDuration(interval client.Interval) (time.Duration, error) {
+	s := string(interval)
+
+	units := []struct {
+		suffix string
+		unit   time.Duration
+	}{
+		{"month", 30 * 24 * time.Hour},
+		{"m", time.Minute},
+		{"h", time.Hour},
+		{"d", 24 * time.Hour},
+		{"w", 7 * 24 * time.Hour},
+	}
+
+	for _, u := range units {
+		if v, ok := strings.CutSuffix(s, u.suffix); ok {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				break
+			}
+
+			return time.Duration(n) * u.unit, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown interval %q", interval)
+}
+
+// KLines time is ISO datetime without zone or unix seconds
+func parseTime(s string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02T15:04:05", s); err == nil {
+		return t, nil
+	}
+
+	if t, err := time.Parse("2006-01-02 15:04:05", s); err == nil {
+		return t, nil
+	}
+
+	sec, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return time.Unix(sec, 0), nil
+}
@@ -0,0 +1,75 @@
+package funding
+
+import (
+	"sync"
+	"time"
+
+	"github.com/leenzstra/backpack-go/client"
+	"github.com/leenzstra/backpack-go/internal/jsonl"
+)
+
+type Action string
+
+const (
+	ActionRequested      Action = "requested"
+	ActionRejected       Action = "rejected"
+	ActionPending        Action = "pending"
+	ActionApproved       Action = "approved"
+	ActionDenied         Action = "denied"
+	ActionSubmitted      Action = "submitted"
+	ActionFailed         Action = "failed"
+	ActionAddressAdded   Action = "address_added"
+	ActionAddressRemoved Action = "address_removed"
+)
+
+type AuditEntry struct {
+	Time      time.Time `json:"time"`
+	Action    Action    `json:"action"`
+	Actor     string    `json:"actor"`
+	RequestID string    `json:"requestId,omitempty"`
+	// Two factor token is never logged
+	Request      *client.WithdrawalRequest `json:"request,omitempty"`
+	Address      *AllowedAddress           `json:"address,omitempty"`
+	Reason       string                    `json:"reason,omitempty"`
+	WithdrawalID int                       `json:"withdrawalId,omitempty"`
+}
+
+// AuditLog is append only record of withdrawal decisions
+type AuditLog interface {
+	Append(entry AuditEntry) error
+}
+
+// MemoryAuditLog keeps entries in process only
+type MemoryAuditLog struct {
+	mu      sync.Mutex
+	entries []AuditEntry
+}
+
+func (l *MemoryAuditLog) Append(entry AuditEntry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.entries = append(l.entries, entry)
+	return nil
+}
+
+func (l *MemoryAuditLog) Entries() []AuditEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return append([]AuditEntry{}, l.entries...)
+}
+
+// FileAuditLog appends entries to file as JSON lines
+type FileAuditLog struct {
+	Path string
+
+	mu sync.Mutex
+}
+
+func (l *FileAuditLog) Append(entry AuditEntry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return jsonl.Append(l.Path, entry)
+}
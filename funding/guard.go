@@ -0,0 +1,506 @@
+package funding
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/leenzstra/backpack-go/client"
+	"github.com/leenzstra/backpack-go/internal/num"
+	"github.com/leenzstra/backpack-go/trading"
+)
+
+var _ client.Capital = (*Guard)(nil)
+
+var (
+	ErrRejected        = errors.New("withdrawal rejected")
+	ErrPendingApproval = errors.New("withdrawal is pending approval")
+	ErrUnknownRequest  = errors.New("unknown withdrawal request")
+	ErrSelfApproval    = errors.New("requester can not approve own withdrawal")
+)
+
+// Actor of withdrawals made through client.Capital interface
+const DefaultActor = "api"
+
+type Rule string
+
+const (
+	RuleAllowlist      Rule = "allowlist"
+	RuleCoolingOff     Rule = "cooling_off"
+	RuleTransactionMax Rule = "transaction_max"
+	RuleDailyMax       Rule = "daily_max"
+	RuleQuantity       Rule = "quantity"
+	RuleAddress        Rule = "address"
+)
+
+// Violation is structured rejection returned by Guard
+type Violation struct {
+	Rule   Rule
+	Symbol string
+	Value  float64
+	Limit  float64
+	Reason string
+}
+
+func (v *Violation) Error() string {
+	return fmt.Sprintf("withdrawal %s %s: %s (value %v, limit %v)", v.Rule, v.Symbol, v.Reason, v.Value, v.Limit)
+}
+
+func (v *Violation) Unwrap() error {
+	return ErrRejected
+}
+
+// PendingError is returned when request waits for second signer
+type PendingError struct {
+	ID string
+}
+
+func (e *PendingError) Error() string {
+	return fmt.Sprintf("withdrawal %s is pending approval", e.ID)
+}
+
+func (e *PendingError) Unwrap() error {
+	return ErrPendingApproval
+}
+
+// Limits of zero value are disabled
+type Limits struct {
+	// Max quantity of one withdrawal per asset
+	MaxPerTransaction map[string]float64
+	// Max quantity withdrawn per asset within last 24 hours
+	MaxDaily map[string]float64
+	// New allowlist entries can not be used before this period ends
+	CoolingOff time.Duration
+	// Requests wait for approval of other actor
+	RequireApproval bool
+}
+
+// AllowedAddress is allowlist entry for asset on blockchain
+type AllowedAddress struct {
+	Symbol     string    `json:"symbol"`
+	Blockchain string    `json:"blockchain"`
+	Address    string    `json:"address"`
+	Label      string    `json:"label,omitempty"`
+	AddedBy    string    `json:"addedBy"`
+	AddedAt    time.Time `json:"addedAt"`
+}
+
+func (a AllowedAddress) matches(req client.WithdrawalRequest) bool {
+	if a.Symbol != req.Symbol || a.Blockchain != req.Blockchain {
+		return false
+	}
+
+	// hex addresses are case insensitive, case is checksum only
+	if strings.HasPrefix(a.Address, "0x") {
+		return strings.EqualFold(a.Address, req.Address)
+	}
+
+	return a.Address == req.Address
+}
+
+type PendingWithdrawal struct {
+	ID          string                   `json:"id"`
+	Request     client.WithdrawalRequest `json:"request"`
+	RequestedBy string                   `json:"requestedBy"`
+	RequestedAt time.Time                `json:"requestedAt"`
+}
+
+type SentWithdrawal struct {
+	Symbol   string    `json:"symbol"`
+	Quantity float64   `json:"quantity"`
+	Time     time.Time `json:"time"`
+}
+
+// State is persisted allowlist, pending requests and sent amounts for daily limit
+type State struct {
+	Allowlist []AllowedAddress    `json:"allowlist"`
+	Pending   []PendingWithdrawal `json:"pending"`
+	Sent      []SentWithdrawal    `json:"sent"`
+}
+
+// Guard checks withdrawals before passing them to next Capital.
+// Every request and decision is written to audit log
+type Guard struct {
+	// Optional, requests are checked against token metadata and balances
+	Tokens *TokenValidator
+
+	next  client.Capital
+	store trading.Store[State]
+	audit AuditLog
+
+	mu     sync.Mutex
+	limits Limits
+	state  State
+}
+
+func NewGuard(next client.Capital, store trading.Store[State], audit AuditLog, limits Limits) (*Guard, error) {
+	state, err := store.Load()
+	if err != nil {
+		return nil, fmt.Errorf("load withdrawal state err: %v", err)
+	}
+
+	return &Guard{next: next, store: store, audit: audit, limits: limits, state: state}, nil
+}
+
+// SetLimits replaces limits at runtime
+func (g *Guard) SetLimits(limits Limits) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.limits = limits
+}
+
+// AddAddress adds allowlist entry, it is usable after cooling off period
+func (g *Guard) AddAddress(actor string, address AllowedAddress) error {
+	if address.Symbol == "" || address.Blockchain == "" || address.Address == "" {
+		return fmt.Errorf("symbol, blockchain and address are required")
+	}
+
+	if err := client.Blockchain(address.Blockchain).ValidateAddress(address.Address); err != nil {
+		return err
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	address.AddedBy = actor
+	address.AddedAt = time.Now()
+
+	for i, a := range g.state.Allowlist {
+		if a.matches(client.WithdrawalRequest{Symbol: address.Symbol, Blockchain: address.Blockchain, Address: address.Address}) {
+			g.state.Allowlist = append(g.state.Allowlist[:i], g.state.Allowlist[i+1:]...)
+			break
+		}
+	}
+
+	g.state.Allowlist = append(g.state.Allowlist, address)
+
+	if err := g.save(); err != nil {
+		return err
+	}
+
+	return g.log(AuditEntry{Action: ActionAddressAdded, Actor: actor, Address: &address})
+}
+
+func (g *Guard) RemoveAddress(actor string, symbol, blockchain, address string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	req := client.WithdrawalRequest{Symbol: symbol, Blockchain: blockchain, Address: address}
+
+	for i, a := range g.state.Allowlist {
+		if !a.matches(req) {
+			continue
+		}
+
+		g.state.Allowlist = append(g.state.Allowlist[:i], g.state.Allowlist[i+1:]...)
+
+		if err := g.save(); err != nil {
+			return err
+		}
+
+		return g.log(AuditEntry{Action: ActionAddressRemoved, Actor: actor, Address: &a})
+	}
+
+	return fmt.Errorf("address %s is not in allowlist for %s on %s", address, symbol, blockchain)
+}
+
+func (g *Guard) Allowlist() []AllowedAddress {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return append([]AllowedAddress{}, g.state.Allowlist...)
+}
+
+func (g *Guard) Pending() []PendingWithdrawal {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return append([]PendingWithdrawal{}, g.state.Pending...)
+}
+
+// Withdraw checks request and submits it, or stores it for approval
+// and returns PendingError if approval is required
+func (g *Guard) Withdraw(actor string, req client.WithdrawalRequest) (*client.Withdrawal, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err := g.log(AuditEntry{Action: ActionRequested, Actor: actor, Request: redact(req)}); err != nil {
+		return nil, err
+	}
+
+	if v := g.check(req); v != nil {
+		return nil, g.reject(actor, "", req, v)
+	}
+
+	if !g.limits.RequireApproval {
+		return g.submit(actor, "", req)
+	}
+
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+
+	// token is not persisted, approver provides fresh one
+	g.state.Pending = append(g.state.Pending, PendingWithdrawal{ID: id, Request: *redact(req), RequestedBy: actor, RequestedAt: time.Now()})
+
+	if err := g.save(); err != nil {
+		return nil, err
+	}
+
+	if err := g.log(AuditEntry{Action: ActionPending, Actor: actor, RequestID: id, Request: redact(req)}); err != nil {
+		return nil, err
+	}
+
+	return nil, &PendingError{ID: id}
+}
+
+// Approve submits pending request, approver must differ from requester.
+// Rules are checked again with current limits. Request stays pending if it is
+// rejected or fails before reaching exchange, so approval can be retried
+func (g *Guard) Approve(approver, id, twoFactorToken string) (*client.Withdrawal, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	_, p, err := g.pending(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if approver == p.RequestedBy {
+		return nil, ErrSelfApproval
+	}
+
+	req := p.Request
+	req.TwoFactorToken = twoFactorToken
+
+	if v := g.check(req); v != nil {
+		return nil, g.reject(approver, id, req, v)
+	}
+
+	if err := g.log(AuditEntry{Action: ActionApproved, Actor: approver, RequestID: id, Request: redact(req)}); err != nil {
+		return nil, err
+	}
+
+	withdrawal, err := g.submit(approver, id, req)
+	if err != nil && !ambiguous(err) {
+		return nil, err
+	}
+
+	// sent or possibly sent, retry could withdraw twice
+	if i, _, perr := g.pending(id); perr == nil {
+		g.state.Pending = append(g.state.Pending[:i], g.state.Pending[i+1:]...)
+	}
+
+	return withdrawal, errors.Join(err, g.save())
+}
+
+// Deny drops pending request
+func (g *Guard) Deny(approver, id, reason string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	i, p, err := g.pending(id)
+	if err != nil {
+		return err
+	}
+
+	g.state.Pending = append(g.state.Pending[:i], g.state.Pending[i+1:]...)
+
+	if err := g.save(); err != nil {
+		return err
+	}
+
+	return g.log(AuditEntry{Action: ActionDenied, Actor: approver, RequestID: id, Request: redact(p.Request), Reason: reason})
+}
+
+// RequestWithdrawal implements client.Capital.
+func (g *Guard) RequestWithdrawal(payload *client.WithdrawalRequest) (*client.Withdrawal, error) {
+	return g.Withdraw(DefaultActor, *payload)
+}
+
+// Balances implements client.Capital.
+func (g *Guard) Balances() (client.Balances, error) {
+	return g.next.Balances()
+}
+
+// Deposits implements client.Capital.
+func (g *Guard) Deposits(limit int64, offset int64) ([]client.Deposit, error) {
+	return g.next.Deposits(limit, offset)
+}
+
+// DepositAddress implements client.Capital.
+func (g *Guard) DepositAddress(blockchain client.Blockchain) (*client.DepositAddress, error) {
+	return g.next.DepositAddress(blockchain)
+}
+
+// Withdrawals implements client.Capital.
+func (g *Guard) Withdrawals(limit int64, offset int64) ([]client.Withdrawal, error) {
+	return g.next.Withdrawals(limit, offset)
+}
+
+func (g *Guard) check(req client.WithdrawalRequest) *Violation {
+	quantity := num.Float(req.Quantity)
+	if quantity <= 0 {
+		return &Violation{Rule: RuleQuantity, Symbol: req.Symbol, Value: quantity, Reason: "quantity must be positive"}
+	}
+
+	if err := client.Blockchain(req.Blockchain).ValidateAddress(req.Address); err != nil {
+		return &Violation{Rule: RuleAddress, Symbol: req.Symbol, Reason: err.Error()}
+	}
+
+	var allowed *AllowedAddress
+	for i, a := range g.state.Allowlist {
+		if a.matches(req) {
+			allowed = &g.state.Allowlist[i]
+		}
+	}
+
+	if allowed == nil {
+		return &Violation{Rule: RuleAllowlist, Symbol: req.Symbol, Reason: fmt.Sprintf("address %s on %s is not allowed", req.Address, req.Blockchain)}
+	}
+
+	if age := time.Since(allowed.AddedAt); age < g.limits.CoolingOff {
+		return &Violation{Rule: RuleCoolingOff, Symbol: req.Symbol, Value: age.Hours(), Limit: g.limits.CoolingOff.Hours(), Reason: "address is in cooling off period"}
+	}
+
+	if limit, ok := g.limits.MaxPerTransaction[req.Symbol]; ok && quantity > limit {
+		return &Violation{Rule: RuleTransactionMax, Symbol: req.Symbol, Value: quantity, Limit: limit, Reason: "withdrawal quantity is too big"}
+	}
+
+	if limit, ok := g.limits.MaxDaily[req.Symbol]; ok {
+		total := quantity + g.sentSince(req.Symbol, time.Now().Add(-24*time.Hour))
+
+		if total > limit {
+			return &Violation{Rule: RuleDailyMax, Symbol: req.Symbol, Value: total, Limit: limit, Reason: "daily withdrawal limit exceeded"}
+		}
+	}
+
+	if g.Tokens != nil {
+		if _, err := g.Tokens.Preview(req); err != nil {
+			var v *Violation
+			if errors.As(err, &v) {
+				return v
+			}
+
+			// fail closed, request can not be checked without metadata
+			return &Violation{Rule: RuleMetadata, Symbol: req.Symbol, Reason: err.Error()}
+		}
+	}
+
+	return nil
+}
+
+func (g *Guard) submit(actor, id string, req client.WithdrawalRequest) (*client.Withdrawal, error) {
+	withdrawal, err := g.next.RequestWithdrawal(&req)
+	if err != nil {
+		errs := []error{err}
+
+		// funds may have left, amount counts for daily limit
+		if ambiguous(err) {
+			g.sent(req)
+			errs = append(errs, g.save())
+		}
+
+		errs = append(errs, g.log(AuditEntry{Action: ActionFailed, Actor: actor, RequestID: id, Request: redact(req), Reason: err.Error()}))
+
+		return nil, errors.Join(errs...)
+	}
+
+	g.sent(req)
+
+	errs := []error{g.save()}
+	errs = append(errs, g.log(AuditEntry{Action: ActionSubmitted, Actor: actor, RequestID: id, Request: redact(req), WithdrawalID: withdrawal.ID}))
+
+	return withdrawal, errors.Join(errs...)
+}
+
+// sent records request for daily limit
+func (g *Guard) sent(req client.WithdrawalRequest) {
+	now := time.Now()
+	g.state.Sent = append(g.state.Sent, SentWithdrawal{Symbol: req.Symbol, Quantity: num.Float(req.Quantity), Time: now})
+
+	// older records do not count for daily limit
+	i := 0
+	for i < len(g.state.Sent) && now.Sub(g.state.Sent[i].Time) > 24*time.Hour {
+		i++
+	}
+	g.state.Sent = g.state.Sent[i:]
+}
+
+// ambiguous reports if failed request may have been executed, only transport
+// errors and 5xx responses do not tell it
+func ambiguous(err error) bool {
+	apiErr := &client.APIError{}
+	if errors.As(err, &apiErr) {
+		return !apiErr.Rejected()
+	}
+
+	return !errors.Is(err, client.ErrNotSent) && !errors.Is(err, client.ErrInvalidAddress) && !errors.Is(err, ErrRejected)
+}
+
+func (g *Guard) reject(actor, id string, req client.WithdrawalRequest, v *Violation) error {
+	if err := g.log(AuditEntry{Action: ActionRejected, Actor: actor, RequestID: id, Request: redact(req), Reason: v.Error()}); err != nil {
+		return errors.Join(v, err)
+	}
+
+	return v
+}
+
+func (g *Guard) pending(id string) (int, PendingWithdrawal, error) {
+	for i, p := range g.state.Pending {
+		if p.ID == id {
+			return i, p, nil
+		}
+	}
+
+	return 0, PendingWithdrawal{}, fmt.Errorf("%w: %s", ErrUnknownRequest, id)
+}
+
+func (g *Guard) sentSince(symbol string, since time.Time) float64 {
+	total := 0.0
+	for _, s := range g.state.Sent {
+		if s.Symbol == symbol && s.Time.After(since) {
+			total += s.Quantity
+		}
+	}
+
+	return total
+}
+
+func (g *Guard) save() error {
+	if err := g.store.Save(g.state); err != nil {
+		return fmt.Errorf("save withdrawal state err: %v", err)
+	}
+
+	return nil
+}
+
+func (g *Guard) log(entry AuditEntry) error {
+	entry.Time = time.Now()
+
+	if err := g.audit.Append(entry); err != nil {
+		return fmt.Errorf("audit log err: %v", err)
+	}
+
+	return nil
+}
+
+func redact(req client.WithdrawalRequest) *client.WithdrawalRequest {
+	req.TwoFactorToken = ""
+	return &req
+}
+
+func newID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
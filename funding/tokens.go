@@ -0,0 +1,192 @@
+package funding
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/leenzstra/backpack-go/client"
+	"github.com/leenzstra/backpack-go/internal/num"
+)
+
+const (
+	RuleBlockchain Rule = "blockchain"
+	RuleDisabled   Rule = "disabled"
+	RuleMinimum    Rule = "minimum"
+	RuleMaximum    Rule = "maximum"
+	RuleBalance    Rule = "balance"
+	RuleMetadata   Rule = "metadata"
+)
+
+// Preview is withdrawal as it will be executed
+type Preview struct {
+	Symbol     string
+	Blockchain string
+	// Amount received on address
+	Net float64
+	Fee float64
+	// Amount debited from balance, quantity and fee
+	Total     float64
+	Available float64
+	Minimum   float64
+	Maximum   float64
+}
+
+type DepositInfo struct {
+	Symbol         string
+	Blockchain     string
+	Address        string
+	Enabled        bool
+	MinimumDeposit float64
+	// Human readable notes to show with address
+	Warnings []string
+}
+
+var _ client.WithdrawalValidator = (*TokenValidator)(nil)
+
+// TokenValidator checks withdrawals against asset token metadata and balances
+type TokenValidator struct {
+	markets client.Markets
+	capital client.Capital
+
+	mu     sync.Mutex
+	tokens map[string]map[string]client.Token
+}
+
+func NewTokenValidator(markets client.Markets, capital client.Capital) *TokenValidator {
+	return &TokenValidator{markets: markets, capital: capital}
+}
+
+// Refresh reloads asset metadata
+func (v *TokenValidator) Refresh() error {
+	assets, err := v.markets.Assets()
+	if err != nil {
+		return fmt.Errorf("assets err: %v", err)
+	}
+
+	tokens := make(map[string]map[string]client.Token, len(assets))
+	for _, a := range assets {
+		tokens[a.Symbol] = make(map[string]client.Token, len(a.Tokens))
+
+		for _, t := range a.Tokens {
+			tokens[a.Symbol][t.Blockchain] = t
+		}
+	}
+
+	v.mu.Lock()
+	v.tokens = tokens
+	v.mu.Unlock()
+
+	return nil
+}
+
+// Preview validates request and returns amounts, errors are *Violation
+func (v *TokenValidator) Preview(req client.WithdrawalRequest) (*Preview, error) {
+	t, err := v.token(req.Symbol, req.Blockchain)
+	if err != nil {
+		return nil, err
+	}
+
+	if !t.WithdrawEnabled {
+		return nil, &Violation{Rule: RuleDisabled, Symbol: req.Symbol, Reason: fmt.Sprintf("withdrawals on %s are disabled", req.Blockchain)}
+	}
+
+	quantity := num.Float(req.Quantity)
+
+	p := &Preview{
+		Symbol:     req.Symbol,
+		Blockchain: req.Blockchain,
+		Net:        quantity,
+		Fee:        num.Float(t.WithdrawalFee),
+		Minimum:    num.Float(t.MinimumWithdrawal),
+		Maximum:    num.Float(t.MaximumWithdrawal),
+	}
+	p.Total = p.Net + p.Fee
+
+	switch {
+	case quantity <= 0:
+		return nil, &Violation{Rule: RuleQuantity, Symbol: req.Symbol, Value: quantity, Reason: "quantity must be positive"}
+	case quantity < p.Minimum:
+		return nil, &Violation{Rule: RuleMinimum, Symbol: req.Symbol, Value: quantity, Limit: p.Minimum, Reason: "quantity is below minimum withdrawal"}
+	case p.Maximum > 0 && quantity > p.Maximum:
+		return nil, &Violation{Rule: RuleMaximum, Symbol: req.Symbol, Value: quantity, Limit: p.Maximum, Reason: "quantity is above maximum withdrawal"}
+	}
+
+	balances, err := v.capital.Balances()
+	if err != nil {
+		return nil, fmt.Errorf("balances err: %v", err)
+	}
+
+	p.Available = num.Float(balances[req.Symbol].Available)
+
+	if p.Total > p.Available {
+		return nil, &Violation{Rule: RuleBalance, Symbol: req.Symbol, Value: p.Total, Limit: p.Available, Reason: "not enough available funds including fee"}
+	}
+
+	return p, nil
+}
+
+// ValidateWithdrawal implements client.WithdrawalValidator
+func (v *TokenValidator) ValidateWithdrawal(req client.WithdrawalRequest) error {
+	_, err := v.Preview(req)
+	return err
+}
+
+// DepositAddress returns address with minimum deposit warnings
+func (v *TokenValidator) DepositAddress(symbol string, blockchain client.Blockchain) (*DepositInfo, error) {
+	t, err := v.token(symbol, string(blockchain))
+	if err != nil {
+		return nil, err
+	}
+
+	info := &DepositInfo{
+		Symbol:         symbol,
+		Blockchain:     string(blockchain),
+		Enabled:        t.DepositEnabled,
+		MinimumDeposit: num.Float(t.MinimumDeposit),
+	}
+
+	if !t.DepositEnabled {
+		info.Warnings = append(info.Warnings, fmt.Sprintf("deposits of %s on %s are disabled, funds sent now may be lost", symbol, blockchain))
+		return info, nil
+	}
+
+	if info.MinimumDeposit > 0 {
+		info.Warnings = append(info.Warnings, fmt.Sprintf("deposits below %s %s are not credited", t.MinimumDeposit, symbol))
+	}
+
+	address, err := v.capital.DepositAddress(blockchain)
+	if err != nil {
+		return nil, err
+	}
+
+	info.Address = address.Address
+
+	return info, nil
+}
+
+func (v *TokenValidator) token(symbol, blockchain string) (client.Token, error) {
+	v.mu.Lock()
+	loaded := v.tokens != nil
+	v.mu.Unlock()
+
+	if !loaded {
+		if err := v.Refresh(); err != nil {
+			return client.Token{}, err
+		}
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	tokens, ok := v.tokens[symbol]
+	if !ok {
+		return client.Token{}, &Violation{Rule: RuleBlockchain, Symbol: symbol, Reason: "unknown asset"}
+	}
+
+	t, ok := tokens[blockchain]
+	if !ok {
+		return client.Token{}, &Violation{Rule: RuleBlockchain, Symbol: symbol, Reason: fmt.Sprintf("asset is not available on %s", blockchain)}
+	}
+
+	return t, nil
+}
@@ -0,0 +1,297 @@
+package funding
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/leenzstra/backpack-go/client"
+	"github.com/leenzstra/backpack-go/trading"
+)
+
+var ErrTransferFailed = errors.New("transfer failed")
+
+const (
+	DefaultWatchInterval = 15 * time.Second
+	// Number of latest records checked per poll
+	DefaultWatchDepth = 100
+)
+
+type TransferKind string
+
+const (
+	KindDeposit    TransferKind = "deposit"
+	KindWithdrawal TransferKind = "withdrawal"
+)
+
+type TransferStatus string
+
+const (
+	TransferPending   TransferStatus = "pending"
+	TransferConfirmed TransferStatus = "confirmed"
+	TransferFailed    TransferStatus = "failed"
+	TransferCancelled TransferStatus = "cancelled"
+)
+
+func (s TransferStatus) IsTerminal() bool {
+	return s != TransferPending
+}
+
+// NormalizeStatus maps exchange deposit and withdrawal statuses to TransferStatus
+func NormalizeStatus(status string) TransferStatus {
+	switch strings.ToLower(status) {
+	case "confirmed", "completed":
+		return TransferConfirmed
+	case "cancelled", "canceled", "expired":
+		return TransferCancelled
+	case "failed", "declined", "refunded", "rejected":
+		return TransferFailed
+	}
+
+	return TransferPending
+}
+
+type TransferEvent struct {
+	Kind     TransferKind
+	ID       int
+	Symbol   string
+	Quantity string
+	Status   TransferStatus
+	// Empty for first seen transfer
+	PrevStatus TransferStatus
+	// Status as returned by exchange
+	RawStatus string
+	// Deposit seen for the first time
+	New bool
+	// One of them is set by kind
+	Deposit    *client.Deposit
+	Withdrawal *client.Withdrawal
+}
+
+// WatcherState is last reported raw status by transfer ID
+type WatcherState struct {
+	Deposits    map[int]string `json:"deposits"`
+	Withdrawals map[int]string `json:"withdrawals"`
+	// Transfers existing on first start are recorded without events
+	DepositsInitialized    bool `json:"depositsInitialized"`
+	WithdrawalsInitialized bool `json:"withdrawalsInitialized"`
+}
+
+// Watcher polls deposits and withdrawals and reports status transitions.
+// Statuses are persisted after delivery, restart repeats only undelivered events
+type Watcher struct {
+	Interval time.Duration
+	Depth    int64
+	OnEvent  func(e TransferEvent)
+	OnError  func(err error)
+
+	capital client.Capital
+	store   trading.Store[WatcherState]
+
+	mu          sync.Mutex
+	state       WatcherState
+	withdrawals map[int]client.Withdrawal
+	channels    []chan TransferEvent
+	changed     chan struct{}
+}
+
+func NewWatcher(capital client.Capital, store trading.Store[WatcherState]) (*Watcher, error) {
+	state, err := store.Load()
+	if err != nil {
+		return nil, fmt.Errorf("load watcher state err: %v", err)
+	}
+
+	if state.Deposits == nil {
+		state.Deposits = make(map[int]string)
+	}
+
+	if state.Withdrawals == nil {
+		state.Withdrawals = make(map[int]string)
+	}
+
+	return &Watcher{
+		Interval:    DefaultWatchInterval,
+		Depth:       DefaultWatchDepth,
+		capital:     capital,
+		store:       store,
+		state:       state,
+		withdrawals: make(map[int]client.Withdrawal),
+		changed:     make(chan struct{}),
+	}, nil
+}
+
+// Events returns channel receiving every event. Reader must keep up,
+// poll blocks until event is delivered
+func (w *Watcher) Events(buffer int) <-chan TransferEvent {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	ch := make(chan TransferEvent, buffer)
+	w.channels = append(w.channels, ch)
+
+	return ch
+}
+
+// Run polls until ctx is done
+func (w *Watcher) Run(ctx context.Context) error {
+	interval := w.Interval
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+
+	for {
+		if err := w.Poll(); err != nil {
+			w.error(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// Poll checks latest deposits and withdrawals once
+func (w *Watcher) Poll() error {
+	depth := w.Depth
+	if depth <= 0 {
+		depth = DefaultWatchDepth
+	}
+
+	deposits, derr := w.capital.Deposits(depth, 0)
+	withdrawals, werr := w.capital.Withdrawals(depth, 0)
+
+	if derr != nil {
+		derr = fmt.Errorf("deposits err: %v", derr)
+	}
+
+	if werr != nil {
+		werr = fmt.Errorf("withdrawals err: %v", werr)
+	}
+
+	events := make([]TransferEvent, 0)
+
+	w.mu.Lock()
+
+	if derr == nil {
+		initial := !w.state.DepositsInitialized
+
+		for i := range deposits {
+			d := deposits[i]
+
+			prev, known := w.state.Deposits[d.ID]
+			if known && prev == d.Status {
+				continue
+			}
+
+			w.state.Deposits[d.ID] = d.Status
+
+			if initial {
+				continue
+			}
+
+			e := TransferEvent{Kind: KindDeposit, ID: d.ID, Symbol: d.Symbol, Quantity: d.Quantity, RawStatus: d.Status, Status: NormalizeStatus(d.Status), New: !known, Deposit: &d}
+			if known {
+				e.PrevStatus = NormalizeStatus(prev)
+			}
+
+			events = append(events, e)
+		}
+
+		w.state.DepositsInitialized = true
+	}
+
+	if werr == nil {
+		initial := !w.state.WithdrawalsInitialized
+
+		for i := range withdrawals {
+			wd := withdrawals[i]
+			w.withdrawals[wd.ID] = wd
+
+			prev, known := w.state.Withdrawals[wd.ID]
+			if known && prev == wd.Status {
+				continue
+			}
+
+			w.state.Withdrawals[wd.ID] = wd.Status
+
+			if initial {
+				continue
+			}
+
+			e := TransferEvent{Kind: KindWithdrawal, ID: wd.ID, Symbol: wd.Symbol, Quantity: wd.Quantity, RawStatus: wd.Status, Status: NormalizeStatus(wd.Status), Withdrawal: &wd}
+			if known {
+				e.PrevStatus = NormalizeStatus(prev)
+			}
+
+			// withdrawals are requested by us, first status is reported too
+			events = append(events, e)
+		}
+
+		w.state.WithdrawalsInitialized = true
+
+		close(w.changed)
+		w.changed = make(chan struct{})
+	}
+
+	channels := w.channels
+
+	w.mu.Unlock()
+
+	for _, e := range events {
+		if w.OnEvent != nil {
+			w.OnEvent(e)
+		}
+
+		for _, ch := range channels {
+			ch <- e
+		}
+	}
+
+	// saved after delivery, events interrupted by crash are repeated
+	w.mu.Lock()
+	serr := w.store.Save(w.state)
+	w.mu.Unlock()
+
+	if serr != nil {
+		serr = fmt.Errorf("save watcher state err: %v", serr)
+	}
+
+	return errors.Join(derr, werr, serr)
+}
+
+// WaitForWithdrawal blocks until withdrawal is confirmed, failed or cancelled.
+// Run must be active to refresh statuses. Returns ErrTransferFailed for failed or cancelled withdrawal
+func (w *Watcher) WaitForWithdrawal(ctx context.Context, id int) (client.Withdrawal, error) {
+	for {
+		w.mu.Lock()
+		wd, ok := w.withdrawals[id]
+		changed := w.changed
+		w.mu.Unlock()
+
+		if ok {
+			switch NormalizeStatus(wd.Status) {
+			case TransferConfirmed:
+				return wd, nil
+			case TransferFailed, TransferCancelled:
+				return wd, fmt.Errorf("%w: withdrawal %d is %s", ErrTransferFailed, id, wd.Status)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return wd, ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+func (w *Watcher) error(err error) {
+	if w.OnError != nil {
+		w.OnError(err)
+	}
+}
@@ -0,0 +1,611 @@
+package grid
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/leenzstra/backpack-go/client"
+	"github.com/leenzstra/backpack-go/internal/num"
+	"github.com/leenzstra/backpack-go/trading"
+)
+
+// API is part of client used by grid bot
+type API interface {
+	client.Orders
+	client.BatchOrders
+	client.History
+	client.Markets
+	client.Capital
+}
+
+type Spacing int
+
+const (
+	Arithmetic Spacing = iota
+	Geometric
+)
+
+type Config struct {
+	Symbol string  `json:"symbol"`
+	Lower  float64 `json:"lower"`
+	Upper  float64 `json:"upper"`
+	// Number of price levels including bounds
+	Levels  int     `json:"levels"`
+	Spacing Spacing `json:"spacing"`
+	// Quote amount split evenly between grid intervals. Levels above last price
+	// are sells of base, so base balance must cover their quantity at start
+	Investment float64 `json:"investment"`
+}
+
+func (c Config) validate() error {
+	switch {
+	case c.Symbol == "":
+		return fmt.Errorf("symbol is required")
+	case c.Lower <= 0 || c.Upper <= c.Lower:
+		return fmt.Errorf("price range must be positive and lower than upper")
+	case c.Levels < 2:
+		return fmt.Errorf("grid needs at least 2 levels")
+	case c.Investment <= 0:
+		return fmt.Errorf("investment must be positive")
+	}
+
+	return nil
+}
+
+// Levels returns grid prices from lower to upper
+func Levels(c Config) []float64 {
+	levels := make([]float64, c.Levels)
+	n := float64(c.Levels - 1)
+
+	for i := range levels {
+		switch c.Spacing {
+		case Geometric:
+			levels[i] = c.Lower * math.Pow(c.Upper/c.Lower, float64(i)/n)
+		default:
+			levels[i] = c.Lower + (c.Upper-c.Lower)*float64(i)/n
+		}
+	}
+
+	return levels
+}
+
+// Slot is order resting on grid level
+type Slot struct {
+	Level    int    `json:"level"`
+	Side     string `json:"side"`
+	Price    string `json:"price"`
+	Quantity string `json:"quantity"`
+	OrderID  string `json:"orderId"`
+	// Price of fill closed by this order, empty for initial ladder
+	OpenPrice string `json:"openPrice,omitempty"`
+	// Order is filled, opposite order is not placed yet
+	Filled bool `json:"filled,omitempty"`
+}
+
+type State struct {
+	Config  Config   `json:"config"`
+	Prices  []string `json:"prices"`
+	Slots   []*Slot  `json:"slots"`
+	Started bool     `json:"started"`
+	// Quote profit of closed buy-sell pairs before fees
+	RealizedProfit float64 `json:"realizedProfit"`
+	RoundTrips     int     `json:"roundTrips"`
+}
+
+func (s *State) slot(level int) *Slot {
+	for _, slot := range s.Slots {
+		if slot.Level == level {
+			return slot
+		}
+	}
+
+	return nil
+}
+
+func (s *State) remove(level int) {
+	for i, slot := range s.Slots {
+		if slot.Level == level {
+			s.Slots = append(s.Slots[:i], s.Slots[i+1:]...)
+			return
+		}
+	}
+}
+
+func (s *State) clone() State {
+	c := *s
+	c.Prices = append([]string{}, s.Prices...)
+	c.Slots = make([]*Slot, len(s.Slots))
+
+	for i, slot := range s.Slots {
+		copied := *slot
+		c.Slots[i] = &copied
+	}
+
+	return c
+}
+
+// Bot keeps ladder of limit orders on grid levels.
+// Filled buy is replaced by sell one level up and filled sell by buy one level down
+type Bot struct {
+	OnError func(err error)
+
+	api       API
+	tracker   *trading.Tracker
+	validator *client.OrderValidator
+	store     trading.Store[State]
+	cfg       Config
+
+	mu    sync.Mutex
+	state State
+}
+
+func New(api API, tracker *trading.Tracker, validator *client.OrderValidator, store trading.Store[State], cfg Config) (*Bot, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("grid config err: %v", err)
+	}
+
+	if validator == nil {
+		validator = client.NewOrderValidator(api)
+	}
+
+	return &Bot{
+		api:       api,
+		tracker:   tracker,
+		validator: validator,
+		store:     store,
+		cfg:       cfg,
+	}, nil
+}
+
+func (b *Bot) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state.clone()
+}
+
+// Start places initial ladder or reconciles saved state with exchange.
+// Tracker must be running to deliver fills
+func (b *Bot) Start() error {
+	state, err := b.store.Load()
+	if err != nil {
+		return fmt.Errorf("load grid state err: %v", err)
+	}
+
+	b.mu.Lock()
+
+	var track []client.Order
+
+	if state.Started {
+		if state.Config != b.cfg {
+			b.mu.Unlock()
+			return fmt.Errorf("saved grid config differs from current, stop old grid first")
+		}
+
+		b.state = state
+		track, err = b.reconcile()
+	} else {
+		track, err = b.init()
+	}
+
+	if serr := b.store.Save(b.state.clone()); serr != nil {
+		err = errors.Join(err, serr)
+	}
+
+	b.mu.Unlock()
+
+	b.tracker.Subscribe(b.onUpdate)
+
+	for _, order := range track {
+		b.tracker.Track(order)
+	}
+
+	return err
+}
+
+// Stop cancels all grid orders and clears saved state
+func (b *Bot) Stop() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ids := make([]string, 0, len(b.state.Slots))
+	for _, slot := range b.state.Slots {
+		if !slot.Filled {
+			ids = append(ids, slot.OrderID)
+		}
+	}
+
+	results, err := b.api.CancelOrdersByID(b.cfg.Symbol, ids)
+	if err != nil {
+		return err
+	}
+
+	errs := make([]error, 0)
+	for _, r := range results {
+		if r.Err != nil {
+			errs = append(errs, fmt.Errorf("cancel %s err: %v", r.OrderID, r.Err))
+		}
+	}
+
+	b.state.Slots = nil
+	b.state.Started = false
+
+	errs = append(errs, b.store.Save(b.state.clone()))
+
+	return errors.Join(errs...)
+}
+
+func (b *Bot) init() ([]client.Order, error) {
+	ticker, err := b.api.Ticker(b.cfg.Symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	last := num.Float(ticker.LastPrice)
+	levels := Levels(b.cfg)
+	quote := b.cfg.Investment / float64(b.cfg.Levels-1)
+
+	b.state = State{Config: b.cfg, Started: true}
+
+	for _, price := range levels {
+		rounded, err := b.validator.RoundPrice(b.cfg.Symbol, num.String(price))
+		if err != nil {
+			return nil, err
+		}
+
+		b.state.Prices = append(b.state.Prices, rounded)
+	}
+
+	// level closest to last price stays empty, it is where next fill lands
+	skip := 0
+	for i, price := range levels {
+		if math.Abs(price-last) < math.Abs(levels[skip]-last) {
+			skip = i
+		}
+	}
+
+	payloads := make([]client.ExecuteOrderPayload, 0, len(levels))
+	slots := make([]*Slot, 0, len(levels))
+
+	for i, price := range levels {
+		if i == skip {
+			continue
+		}
+
+		side := client.SideBid
+		if price > last {
+			side = client.SideAsk
+		}
+
+		slot := &Slot{Level: i, Side: string(side), Price: b.state.Prices[i]}
+
+		payload, err := b.payload(slot, num.String(quote/price))
+		if err != nil {
+			return nil, err
+		}
+
+		slot.Quantity = payload.Quantity
+		slots = append(slots, slot)
+		payloads = append(payloads, payload)
+	}
+
+	if err := b.checkFunds(slots); err != nil {
+		// nothing is placed, grid is not started
+		b.state = State{}
+		return nil, err
+	}
+
+	results, err := b.api.ExecuteOrders(payloads)
+	if err != nil {
+		return nil, err
+	}
+
+	track := make([]client.Order, 0, len(results))
+	errs := make([]error, 0)
+
+	for _, r := range results {
+		if r.Err != nil {
+			errs = append(errs, fmt.Errorf("grid level %d err: %v", slots[r.Index].Level, r.Err))
+			continue
+		}
+
+		slots[r.Index].OrderID = r.OrderID
+		b.state.Slots = append(b.state.Slots, slots[r.Index])
+		track = append(track, r.Order)
+	}
+
+	return track, errors.Join(errs...)
+}
+
+// checkFunds checks that balances cover initial ladder, bids lock quote and asks lock base
+func (b *Bot) checkFunds(slots []*Slot) error {
+	market, err := b.validator.Market(b.cfg.Symbol)
+	if err != nil {
+		return err
+	}
+
+	var base, quote float64
+	for _, slot := range slots {
+		if slot.Side == string(client.SideAsk) {
+			base += num.Float(slot.Quantity)
+		} else {
+			quote += num.Float(slot.Quantity) * num.Float(slot.Price)
+		}
+	}
+
+	balances, err := b.api.Balances()
+	if err != nil {
+		return fmt.Errorf("balances err: %v", err)
+	}
+
+	if available := num.Float(balances[market.BaseSymbol].Available); base > available {
+		return fmt.Errorf("grid sells need %v %s, available %v", base, market.BaseSymbol, available)
+	}
+
+	if available := num.Float(balances[market.QuoteSymbol].Available); quote > available {
+		return fmt.Errorf("grid buys need %v %s, available %v", quote, market.QuoteSymbol, available)
+	}
+
+	return nil
+}
+
+// Retry places opposite orders of filled slots which failed before
+func (b *Bot) Retry() error {
+	b.mu.Lock()
+
+	track := make([]client.Order, 0)
+	errs := make([]error, 0)
+
+	for _, slot := range append([]*Slot{}, b.state.Slots...) {
+		if !slot.Filled {
+			continue
+		}
+
+		order, err := b.filled(slot)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if order != nil {
+			track = append(track, order)
+		}
+	}
+
+	if len(track) > 0 {
+		if err := b.store.Save(b.state.clone()); err != nil {
+			errs = append(errs, fmt.Errorf("save grid state err: %v", err))
+		}
+	}
+
+	b.mu.Unlock()
+
+	for _, order := range track {
+		b.tracker.Track(order)
+	}
+
+	return errors.Join(errs...)
+}
+
+// Run calls Retry every interval until ctx is done, errors go to OnError
+func (b *Bot) Run(ctx context.Context, interval time.Duration) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+
+		if err := b.Retry(); err != nil {
+			b.error(err)
+		}
+	}
+}
+
+// reconcile checks saved slots against exchange after restart
+func (b *Bot) reconcile() ([]client.Order, error) {
+	open, err := b.api.OpenOrders(b.cfg.Symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[string]client.Order, len(open))
+	for _, order := range open {
+		byID[order.Base().ID] = order
+	}
+
+	track := make([]client.Order, 0)
+	errs := make([]error, 0)
+
+	for _, slot := range append([]*Slot{}, b.state.Slots...) {
+		if order, ok := byID[slot.OrderID]; ok {
+			track = append(track, order)
+			continue
+		}
+
+		if slot.Filled {
+			order, err := b.filled(slot)
+			if err != nil {
+				errs = append(errs, err)
+			} else if order != nil {
+				track = append(track, order)
+			}
+
+			continue
+		}
+
+		history, err := b.api.OrderHistory(slot.OrderID, b.cfg.Symbol, 0, 1)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		status := client.StatusCancelled
+		for _, order := range history {
+			if order.Base().ID == slot.OrderID {
+				status = order.Base().Status
+			}
+		}
+
+		if status == client.StatusFilled {
+			order, err := b.filled(slot)
+			if err != nil {
+				errs = append(errs, err)
+			} else if order != nil {
+				track = append(track, order)
+			}
+
+			continue
+		}
+
+		// cancelled outside of bot, restore level
+		order, err := b.place(slot)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		track = append(track, order)
+	}
+
+	return track, errors.Join(errs...)
+}
+
+func (b *Bot) onUpdate(o trading.TrackedOrder) {
+	if o.Status != client.StatusFilled {
+		return
+	}
+
+	b.mu.Lock()
+
+	var slot *Slot
+	for _, s := range b.state.Slots {
+		if s.OrderID == o.ID && !s.Filled {
+			slot = s
+		}
+	}
+
+	if slot == nil {
+		b.mu.Unlock()
+		return
+	}
+
+	order, err := b.filled(slot)
+	if err != nil {
+		b.error(err)
+	}
+
+	if err := b.store.Save(b.state.clone()); err != nil {
+		b.error(fmt.Errorf("save grid state err: %v", err))
+	}
+
+	b.mu.Unlock()
+
+	b.tracker.Forget(o.ID)
+
+	if order != nil {
+		b.tracker.Track(order)
+	}
+}
+
+// filled places opposite order one level away and books profit of slot.
+// Slot stays in state as filled until opposite order is placed
+func (b *Bot) filled(slot *Slot) (client.Order, error) {
+	slot.Filled = true
+
+	next := &Slot{Quantity: slot.Quantity, OpenPrice: slot.Price}
+
+	if slot.Side == string(client.SideBid) {
+		next.Level = slot.Level + 1
+		next.Side = string(client.SideAsk)
+	} else {
+		next.Level = slot.Level - 1
+		next.Side = string(client.SideBid)
+	}
+
+	if next.Level < 0 || next.Level >= len(b.state.Prices) {
+		b.book(slot)
+		return nil, nil
+	}
+
+	if b.state.slot(next.Level) != nil {
+		return nil, fmt.Errorf("grid level %d is already occupied", next.Level)
+	}
+
+	next.Price = b.state.Prices[next.Level]
+
+	order, err := b.place(next)
+	if err != nil {
+		return nil, err
+	}
+
+	b.book(slot)
+
+	return order, nil
+}
+
+// book removes filled slot and adds its profit if it closed round trip
+func (b *Bot) book(slot *Slot) {
+	if slot.OpenPrice != "" {
+		price := num.Float(slot.Price)
+		quantity := num.Float(slot.Quantity)
+		open := num.Float(slot.OpenPrice)
+
+		profit := (price - open) * quantity
+		if slot.Side == string(client.SideBid) {
+			profit = (open - price) * quantity
+		}
+
+		b.state.RealizedProfit += profit
+		b.state.RoundTrips++
+	}
+
+	b.state.remove(slot.Level)
+}
+
+// place submits slot order and stores it in state
+func (b *Bot) place(slot *Slot) (client.Order, error) {
+	payload, err := b.payload(slot, slot.Quantity)
+	if err != nil {
+		return nil, err
+	}
+
+	order, err := b.api.ExecuteOrder(payload)
+	if err != nil {
+		return nil, fmt.Errorf("grid level %d err: %v", slot.Level, err)
+	}
+
+	slot.OrderID = order.Base().ID
+
+	b.state.remove(slot.Level)
+	b.state.Slots = append(b.state.Slots, slot)
+
+	sort.Slice(b.state.Slots, func(i, j int) bool { return b.state.Slots[i].Level < b.state.Slots[j].Level })
+
+	return order, nil
+}
+
+func (b *Bot) payload(slot *Slot, quantity string) (client.ExecuteOrderPayload, error) {
+	payload := client.ExecuteOrderPayload{
+		OrderType: string(client.OrderTypeLimit),
+		Price:     slot.Price,
+		Quantity:  quantity,
+		Side:      slot.Side,
+		Symbol:    b.cfg.Symbol,
+	}
+
+	err := b.validator.Apply(&payload)
+
+	return payload, err
+}
+
+func (b *Bot) error(err error) {
+	if b.OnError != nil {
+		b.OnError(err)
+	}
+}
@@ -0,0 +1,32 @@
+package jsonl
+
+import (
+	"encoding/json"
+	"os"
+)
+
+// Append writes value to file as one JSON line and syncs it,
+// record must survive crash right after it is written
+func Append(path string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
@@ -0,0 +1,88 @@
+package num
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Float parses decimal string from API, empty or invalid value is zero
+func Float(s string) float64 {
+	if s == "" {
+		return 0
+	}
+
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0
+	}
+
+	return f
+}
+
+// String formats float as shortest decimal string accepted by API
+func String(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// Add sums decimal strings exactly, result keeps the longest precision of operands
+func Add(a, b string) (string, error) {
+	x, y, err := rats(a, b)
+	if err != nil {
+		return "", err
+	}
+
+	return x.Add(x, y).FloatString(max(decimals(a), decimals(b))), nil
+}
+
+// Sub subtracts decimal strings exactly, result keeps the longest precision of operands
+func Sub(a, b string) (string, error) {
+	x, y, err := rats(a, b)
+	if err != nil {
+		return "", err
+	}
+
+	return x.Sub(x, y).FloatString(max(decimals(a), decimals(b))), nil
+}
+
+// Cmp compares decimal strings exactly, "1" and "1.0" are equal
+func Cmp(a, b string) (int, error) {
+	x, y, err := rats(a, b)
+	if err != nil {
+		return 0, err
+	}
+
+	return x.Cmp(y), nil
+}
+
+func rats(a, b string) (*big.Rat, *big.Rat, error) {
+	x, ok := new(big.Rat).SetString(orZero(a))
+	if !ok {
+		return nil, nil, fmt.Errorf("invalid decimal %q", a)
+	}
+
+	y, ok := new(big.Rat).SetString(orZero(b))
+	if !ok {
+		return nil, nil, fmt.Errorf("invalid decimal %q", b)
+	}
+
+	return x, y, nil
+}
+
+func orZero(s string) string {
+	if s == "" {
+		return "0"
+	}
+
+	return s
+}
+
+func decimals(s string) int {
+	_, frac, ok := strings.Cut(s, ".")
+	if !ok {
+		return 0
+	}
+
+	return len(strings.TrimRight(frac, "0"))
+}
@@ -0,0 +1,95 @@
+package ledger
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/leenzstra/backpack-go/internal/num"
+)
+
+const timeLayout = time.RFC3339
+
+// WriteCSV writes one row per posting
+func WriteCSV(w io.Writer, l *Ledger) error {
+	cw := csv.NewWriter(w)
+
+	if err := cw.Write([]string{"time", "transaction", "type", "account", "asset", "amount", "description"}); err != nil {
+		return err
+	}
+
+	for _, tx := range l.Transactions {
+		for _, p := range tx.Postings {
+			row := []string{tx.Time.Format(timeLayout), tx.ID, string(tx.Type), p.Account, p.Asset, num.String(p.Amount), tx.Description}
+			if err := cw.Write(row); err != nil {
+				return err
+			}
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteJSONL writes one transaction per line
+func WriteJSONL(w io.Writer, l *Ledger) error {
+	enc := json.NewEncoder(w)
+
+	for _, tx := range l.Transactions {
+		if err := enc.Encode(tx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteAccounting writes FIFO disposals with realized gains, fees as separate
+// entries and lots open at end of range. Amounts are in ledger quote
+func WriteAccounting(w io.Writer, l *Ledger) error {
+	cw := csv.NewWriter(w)
+
+	header := []string{"kind", "time", "ref", "asset", "quantity", "acquired", "proceeds", "cost_basis", "gain", "fee_value", "quote"}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	rows := make([][]string, 0, len(l.Disposals)+len(l.Fees)+len(l.Lots))
+
+	for _, d := range l.Disposals {
+		rows = append(rows, []string{
+			"disposal", d.Time.Format(timeLayout), d.Ref, d.Asset, num.String(d.Quantity), formatTime(d.Acquired),
+			num.String(d.Proceeds), num.String(d.CostBasis), num.String(d.Gain), "", l.Quote,
+		})
+	}
+
+	for _, f := range l.Fees {
+		rows = append(rows, []string{
+			"fee", f.Time.Format(timeLayout), f.Ref, f.Asset, num.String(f.Quantity), "",
+			"", "", "", num.String(f.Value), l.Quote,
+		})
+	}
+
+	for _, lot := range l.Lots {
+		rows = append(rows, []string{
+			"open_lot", formatTime(l.To), lot.Ref, lot.Asset, num.String(lot.Quantity), formatTime(lot.Acquired),
+			"", num.String(lot.Cost), "", "", l.Quote,
+		})
+	}
+
+	if err := cw.WriteAll(rows); err != nil {
+		return err
+	}
+
+	return cw.Error()
+}
+
+// formatTime leaves unknown time empty, e.g. lot acquired before ledger range
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.Format(timeLayout)
+}
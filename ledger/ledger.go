@@ -0,0 +1,424 @@
+package ledger
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/leenzstra/backpack-go/client"
+	"github.com/leenzstra/backpack-go/funding"
+	"github.com/leenzstra/backpack-go/internal/num"
+	"github.com/leenzstra/backpack-go/portfolio"
+)
+
+type TxType string
+
+const (
+	TxDeposit    TxType = "deposit"
+	TxWithdrawal TxType = "withdrawal"
+	TxTrade      TxType = "trade"
+)
+
+// Accounts of postings, asset is kept in posting
+const (
+	AccountExchange = "assets:exchange"
+	AccountExternal = "equity:external"
+	AccountTrading  = "equity:trading"
+	AccountFees     = "expenses:fees"
+)
+
+type Posting struct {
+	Account string  `json:"account"`
+	Asset   string  `json:"asset"`
+	Amount  float64 `json:"amount"`
+}
+
+// Transaction is balanced set of postings, sum per asset is zero
+type Transaction struct {
+	ID          string    `json:"id"`
+	Time        time.Time `json:"time"`
+	Type        TxType    `json:"type"`
+	Description string    `json:"description"`
+	Postings    []Posting `json:"postings"`
+}
+
+type Lot struct {
+	Asset    string    `json:"asset"`
+	Quantity float64   `json:"quantity"`
+	Cost     float64   `json:"cost"`
+	Acquired time.Time `json:"acquired"`
+	Ref      string    `json:"ref"`
+}
+
+// Disposal is sale of lot part or fee paid with it, amounts are in ledger quote
+type Disposal struct {
+	Time      time.Time `json:"time"`
+	Ref       string    `json:"ref"`
+	Asset     string    `json:"asset"`
+	Quantity  float64   `json:"quantity"`
+	Acquired  time.Time `json:"acquired"`
+	Proceeds  float64   `json:"proceeds"`
+	CostBasis float64   `json:"costBasis"`
+	Gain      float64   `json:"gain"`
+}
+
+type Fee struct {
+	Time     time.Time `json:"time"`
+	Ref      string    `json:"ref"`
+	Asset    string    `json:"asset"`
+	Quantity float64   `json:"quantity"`
+	// Value in ledger quote at fee time
+	Value float64 `json:"value"`
+}
+
+type Ledger struct {
+	From         time.Time
+	To           time.Time
+	Quote        string
+	Transactions []Transaction
+	Disposals    []Disposal
+	Fees         []Fee
+	// Lots left open at end of range
+	Lots []Lot
+	// Refs of events which could not be priced in quote, their cost basis is zero
+	Unpriced []string
+}
+
+// Exporter builds ledger from deposits, withdrawals and fills
+type Exporter struct {
+	capital client.Capital
+	history client.History
+	prices  *portfolio.HistoricalPrices
+}
+
+func NewExporter(capital client.Capital, history client.History, markets client.Markets) *Exporter {
+	return &Exporter{capital: capital, history: history, prices: portfolio.NewHistoricalPrices(markets)}
+}
+
+type event struct {
+	time       time.Time
+	deposit    *client.Deposit
+	withdrawal *client.Withdrawal
+	fill       *client.Fill
+}
+
+// Build loads history between from and to and normalizes it into ledger with
+// cost basis in quote. Lots acquired before from are unknown and not included
+func (e *Exporter) Build(quote string, from, to time.Time) (*Ledger, error) {
+	events, err := e.events(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &Ledger{From: from, To: to, Quote: quote}
+	b := &builder{ledger: l, prices: e.prices, lots: make(map[string][]Lot)}
+
+	for _, ev := range events {
+		switch {
+		case ev.deposit != nil:
+			b.deposit(ev.time, ev.deposit)
+		case ev.withdrawal != nil:
+			b.withdrawal(ev.time, ev.withdrawal)
+		case ev.fill != nil:
+			if err := b.fill(ev.time, ev.fill); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	assets := make([]string, 0, len(b.lots))
+	for asset := range b.lots {
+		assets = append(assets, asset)
+	}
+
+	sort.Strings(assets)
+
+	for _, asset := range assets {
+		l.Lots = append(l.Lots, b.lots[asset]...)
+	}
+
+	return l, nil
+}
+
+func (e *Exporter) events(from, to time.Time) ([]event, error) {
+	ctx := context.Background()
+	events := make([]event, 0)
+
+	in := func(t time.Time) bool {
+		return (from.IsZero() || !t.Before(from)) && (to.IsZero() || !t.After(to))
+	}
+
+	// deposits and withdrawals are newest first
+	deposits := client.NewPager(client.DepositsPage(e.capital))
+	deposits.Prefetch = true
+
+	if !from.IsZero() {
+		deposits.Stop = client.StopBefore(from, client.Deposit.Time)
+	}
+
+	for deposits.Next(ctx) {
+		d := deposits.Item()
+
+		t, err := d.Time()
+		if err != nil {
+			return nil, fmt.Errorf("deposit %d timestamp err: %v", d.ID, err)
+		}
+
+		if in(t) && isConfirmed(d.Status) {
+			events = append(events, event{time: t, deposit: &d})
+		}
+	}
+
+	if err := deposits.Err(); err != nil {
+		return nil, fmt.Errorf("deposits err: %v", err)
+	}
+
+	withdrawals := client.NewPager(client.WithdrawalsPage(e.capital))
+	withdrawals.Prefetch = true
+
+	if !from.IsZero() {
+		withdrawals.Stop = client.StopBefore(from, client.Withdrawal.Time)
+	}
+
+	for withdrawals.Next(ctx) {
+		w := withdrawals.Item()
+
+		t, err := w.Time()
+		if err != nil {
+			return nil, fmt.Errorf("withdrawal %d timestamp err: %v", w.ID, err)
+		}
+
+		if in(t) && isConfirmed(w.Status) {
+			events = append(events, event{time: t, withdrawal: &w})
+		}
+	}
+
+	if err := withdrawals.Err(); err != nil {
+		return nil, fmt.Errorf("withdrawals err: %v", err)
+	}
+
+	fills := client.NewPager(client.FillHistoryPage(e.history, "", "", from, to))
+	fills.Prefetch = true
+
+	for fills.Next(ctx) {
+		f := fills.Item()
+
+		t, err := f.Time()
+		if err != nil {
+			return nil, fmt.Errorf("fill %d timestamp err: %v", f.TradeID, err)
+		}
+
+		events = append(events, event{time: t, fill: &f})
+	}
+
+	if err := fills.Err(); err != nil {
+		return nil, fmt.Errorf("fill history err: %v", err)
+	}
+
+	sort.SliceStable(events, func(i, j int) bool { return events[i].time.Before(events[j].time) })
+
+	return events, nil
+}
+
+// builder keeps FIFO lots per asset while events are applied in time order
+type builder struct {
+	ledger *Ledger
+	prices *portfolio.HistoricalPrices
+	lots   map[string][]Lot
+}
+
+func (b *builder) deposit(t time.Time, d *client.Deposit) {
+	ref := fmt.Sprintf("deposit-%d", d.ID)
+	quantity := num.Float(d.Quantity)
+
+	b.ledger.Transactions = append(b.ledger.Transactions, Transaction{
+		ID:          ref,
+		Time:        t,
+		Type:        TxDeposit,
+		Description: fmt.Sprintf("deposit %s %s from %s", d.Quantity, d.Symbol, d.FromAddress),
+		Postings: []Posting{
+			{Account: AccountExchange, Asset: d.Symbol, Amount: quantity},
+			{Account: AccountExternal, Asset: d.Symbol, Amount: -quantity},
+		},
+	})
+
+	// deposited asset is acquired at market value
+	b.acquire(d.Symbol, quantity, b.value(d.Symbol, quantity, t, ref), t, ref)
+}
+
+func (b *builder) withdrawal(t time.Time, w *client.Withdrawal) {
+	ref := fmt.Sprintf("withdrawal-%d", w.ID)
+	quantity := num.Float(w.Quantity)
+	fee := num.Float(w.Fee)
+
+	tx := Transaction{
+		ID:          ref,
+		Time:        t,
+		Type:        TxWithdrawal,
+		Description: fmt.Sprintf("withdrawal %s %s to %s", w.Quantity, w.Symbol, w.ToAddress),
+		Postings: []Posting{
+			{Account: AccountExchange, Asset: w.Symbol, Amount: -quantity},
+			{Account: AccountExternal, Asset: w.Symbol, Amount: quantity},
+		},
+	}
+
+	// transfer out is not sale, lots leave without gain
+	b.consume(w.Symbol, quantity)
+
+	if fee > 0 {
+		tx.Postings = append(tx.Postings,
+			Posting{Account: AccountExchange, Asset: w.Symbol, Amount: -fee},
+			Posting{Account: AccountFees, Asset: w.Symbol, Amount: fee},
+		)
+
+		b.fee(t, ref, w.Symbol, fee)
+	}
+
+	b.ledger.Transactions = append(b.ledger.Transactions, tx)
+}
+
+func (b *builder) fill(t time.Time, f *client.Fill) error {
+	base, quote, err := b.prices.Assets(f.Symbol)
+	if err != nil {
+		return err
+	}
+
+	ref := fmt.Sprintf("trade-%d", f.TradeID)
+	quantity := num.Float(f.Quantity)
+	notional := quantity * num.Float(f.Price)
+	fee := num.Float(f.Fee)
+
+	got, gotQty, gave, gaveQty := base, quantity, quote, notional
+	if f.Side == string(client.SideAsk) {
+		got, gotQty, gave, gaveQty = quote, notional, base, quantity
+	}
+
+	tx := Transaction{
+		ID:          ref,
+		Time:        t,
+		Type:        TxTrade,
+		Description: fmt.Sprintf("%s %s %s at %s", f.Side, f.Quantity, f.Symbol, f.Price),
+		Postings: []Posting{
+			{Account: AccountExchange, Asset: got, Amount: gotQty},
+			{Account: AccountTrading, Asset: got, Amount: -gotQty},
+			{Account: AccountTrading, Asset: gave, Amount: gaveQty},
+			{Account: AccountExchange, Asset: gave, Amount: -gaveQty},
+		},
+	}
+
+	// both sides have same market value, it is proceeds of given and cost of received asset
+	value := b.value(quote, notional, t, ref)
+
+	b.dispose(gave, gaveQty, value, t, ref)
+	b.acquire(got, gotQty, value, t, ref)
+
+	if fee > 0 {
+		tx.Postings = append(tx.Postings,
+			Posting{Account: AccountExchange, Asset: f.FeeSymbol, Amount: -fee},
+			Posting{Account: AccountFees, Asset: f.FeeSymbol, Amount: fee},
+		)
+
+		b.fee(t, ref, f.FeeSymbol, fee)
+	}
+
+	b.ledger.Transactions = append(b.ledger.Transactions, tx)
+
+	return nil
+}
+
+func (b *builder) acquire(asset string, quantity, cost float64, t time.Time, ref string) {
+	// quote currency itself has no gains
+	if asset == b.ledger.Quote || quantity <= 0 {
+		return
+	}
+
+	b.lots[asset] = append(b.lots[asset], Lot{Asset: asset, Quantity: quantity, Cost: cost, Acquired: t, Ref: ref})
+}
+
+// dispose sells quantity from oldest lots and records gain per lot part
+func (b *builder) dispose(asset string, quantity, proceeds float64, t time.Time, ref string) {
+	if asset == b.ledger.Quote || quantity <= 0 {
+		return
+	}
+
+	perUnit := proceeds / quantity
+
+	for _, part := range b.take(asset, quantity) {
+		d := Disposal{
+			Time:      t,
+			Ref:       ref,
+			Asset:     asset,
+			Quantity:  part.Quantity,
+			Acquired:  part.Acquired,
+			Proceeds:  part.Quantity * perUnit,
+			CostBasis: part.Cost,
+		}
+		d.Gain = d.Proceeds - d.CostBasis
+
+		b.ledger.Disposals = append(b.ledger.Disposals, d)
+	}
+}
+
+// consume removes quantity from lots without gain
+func (b *builder) consume(asset string, quantity float64) {
+	if asset == b.ledger.Quote {
+		return
+	}
+
+	b.take(asset, quantity)
+}
+
+// take removes quantity from oldest lots, part without known lot has zero cost
+func (b *builder) take(asset string, quantity float64) []Lot {
+	parts := make([]Lot, 0)
+	lots := b.lots[asset]
+
+	for quantity > 1e-12 && len(lots) > 0 {
+		lot := &lots[0]
+		size := min(lot.Quantity, quantity)
+		cost := lot.Cost * size / lot.Quantity
+
+		parts = append(parts, Lot{Asset: asset, Quantity: size, Cost: cost, Acquired: lot.Acquired, Ref: lot.Ref})
+
+		lot.Quantity -= size
+		lot.Cost -= cost
+		quantity -= size
+
+		if lot.Quantity <= 1e-12 {
+			lots = lots[1:]
+		}
+	}
+
+	if quantity > 1e-12 {
+		// acquired before ledger range
+		parts = append(parts, Lot{Asset: asset, Quantity: quantity})
+	}
+
+	b.lots[asset] = lots
+
+	return parts
+}
+
+// fee records fee, asset paid is disposed at its market value
+func (b *builder) fee(t time.Time, ref, asset string, quantity float64) {
+	value := b.value(asset, quantity, t, ref)
+
+	b.ledger.Fees = append(b.ledger.Fees, Fee{Time: t, Ref: ref, Asset: asset, Quantity: quantity, Value: value})
+	b.dispose(asset, quantity, value, t, ref)
+}
+
+// value converts quantity of asset to ledger quote at t
+func (b *builder) value(asset string, quantity float64, t time.Time, ref string) float64 {
+	price, err := b.prices.Price(asset, b.ledger.Quote, t)
+	if err != nil {
+		b.ledger.Unpriced = append(b.ledger.Unpriced, ref)
+		return 0
+	}
+
+	return quantity * price
+}
+
+func isConfirmed(status string) bool {
+	return funding.NormalizeStatus(status) == funding.TransferConfirmed
+}
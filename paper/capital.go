@@ -0,0 +1,45 @@
+package paper
+
+import (
+	"fmt"
+
+	"github.com/leenzstra/backpack-go/client"
+	"github.com/leenzstra/backpack-go/internal/num"
+)
+
+// Balances implements client.Capital.
+func (e *Exchange) Balances() (client.Balances, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	balances := make(client.Balances, len(e.balances))
+	for asset, b := range e.balances {
+		balances[asset] = client.Balance{
+			Available: num.String(b.available),
+			Locked:    num.String(b.locked),
+			Staked:    "0",
+		}
+	}
+
+	return balances, nil
+}
+
+// Deposits implements client.Capital.
+func (e *Exchange) Deposits(limit int64, offset int64) ([]client.Deposit, error) {
+	return []client.Deposit{}, nil
+}
+
+// DepositAddress implements client.Capital.
+func (e *Exchange) DepositAddress(blockchain client.Blockchain) (*client.DepositAddress, error) {
+	return nil, ErrNotSupported
+}
+
+// Withdrawals implements client.Capital.
+func (e *Exchange) Withdrawals(limit int64, offset int64) ([]client.Withdrawal, error) {
+	return []client.Withdrawal{}, nil
+}
+
+// RequestWithdrawal implements client.Capital.
+func (e *Exchange) RequestWithdrawal(payload *client.WithdrawalRequest) (*client.Withdrawal, error) {
+	return nil, fmt.Errorf("%w: %w", client.ErrNotSent, ErrNotSupported)
+}
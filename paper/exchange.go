@@ -0,0 +1,725 @@
+package paper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/leenzstra/backpack-go/client"
+	"github.com/leenzstra/backpack-go/internal/num"
+)
+
+var (
+	_ client.Orders      = (*Exchange)(nil)
+	_ client.BatchOrders = (*Exchange)(nil)
+	_ client.Capital     = (*Exchange)(nil)
+	_ client.History     = (*Exchange)(nil)
+)
+
+var (
+	ErrNotFound          = errors.New("order not found")
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	ErrNotSupported      = errors.New("not supported in paper mode")
+	ErrWouldCross        = errors.New("post only order would cross")
+	ErrNoLiquidity       = errors.New("not enough liquidity")
+)
+
+const DefaultBookInterval = time.Second
+
+const timestampLayout = "2006-01-02T15:04:05.000"
+
+type Config struct {
+	// Fee rates as fraction of notional, e.g. 0.001
+	MakerFee float64
+	TakerFee float64
+	// Delay before order reaches simulated matching
+	Latency time.Duration
+	// 0-1, share of untaken book level size available to fill resting order per update.
+	// Zero fills whole level
+	FillRatio float64
+	// Initial available balances
+	Balances map[string]float64
+}
+
+type balance struct {
+	available float64
+	locked    float64
+}
+
+type order struct {
+	payload  client.ExecuteOrderPayload
+	id       string
+	created  time.Time
+	status   string
+	quantity float64
+	price    float64
+	// Market order by quote quantity
+	quoteLimit    float64
+	executed      float64
+	executedQuote float64
+	// Funds locked for resting part
+	locked float64
+	// Mid price on submit, side of trigger is decided by it
+	triggerRef float64
+}
+
+func (o *order) remaining() float64 {
+	return math.Max(o.quantity-o.executed, 0)
+}
+
+func (o *order) view() client.Order {
+	base := client.BaseOrder{
+		OrderType:             o.payload.OrderType,
+		ID:                    o.id,
+		ClientID:              o.payload.ClientID,
+		Symbol:                o.payload.Symbol,
+		Side:                  o.payload.Side,
+		Quantity:              num.String(o.quantity),
+		ExecutedQuantity:      num.String(o.executed),
+		ExecutedQuoteQuantity: num.String(o.executedQuote),
+		TriggerPrice:          o.payload.TriggerPrice,
+		TimeInForce:           o.payload.TimeInForce,
+		SelfTradePrevention:   o.payload.SelfTradePrevention,
+		Status:                o.status,
+		CreatedAt:             int(o.created.UnixMilli()),
+	}
+
+	if o.payload.OrderType == string(client.OrderTypeLimit) {
+		return &client.LimitOrder{BaseOrder: base, Price: o.payload.Price, PostOnly: o.payload.PostOnly}
+	}
+
+	return &client.MarketOrder{BaseOrder: base, QuoteQuantity: o.payload.QuoteQuantity}
+}
+
+// Exchange simulates private API against live public market data.
+// Resting orders are matched on book updates from Run or OnDepth
+type Exchange struct {
+	cfg     Config
+	markets client.Markets
+	meta    *client.OrderValidator
+
+	mu       sync.Mutex
+	balances map[string]*balance
+	orders   map[string]*order
+	closed   []*order
+	fills    []client.Fill
+	seq      int
+	// Book liquidity used by simulated fills per symbol, kept while level stays in book
+	taken map[string]map[levelKey]float64
+}
+
+func New(markets client.Markets, cfg Config) *Exchange {
+	e := &Exchange{
+		cfg:      cfg,
+		markets:  markets,
+		meta:     client.NewOrderValidator(markets),
+		balances: make(map[string]*balance),
+		orders:   make(map[string]*order),
+		taken:    make(map[string]map[levelKey]float64),
+	}
+
+	for asset, amount := range cfg.Balances {
+		e.balances[asset] = &balance{available: amount}
+	}
+
+	return e
+}
+
+// Credit adds simulated balance
+func (e *Exchange) Credit(asset string, amount float64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.balance(asset).available += amount
+}
+
+// ExecuteOrder implements client.Orders.
+func (e *Exchange) ExecuteOrder(payload client.ExecuteOrderPayload) (client.Order, error) {
+	if err := payload.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %w", client.ErrInvalidOrder, err)
+	}
+
+	market, err := e.meta.Market(payload.Symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	time.Sleep(e.cfg.Latency)
+
+	depth, err := e.markets.Depth(payload.Symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.prune(payload.Symbol, depth)
+
+	e.seq++
+
+	o := &order{
+		payload:    payload,
+		id:         strconv.Itoa(e.seq),
+		created:    time.Now(),
+		status:     client.StatusNew,
+		quantity:   num.Float(payload.Quantity),
+		price:      num.Float(payload.Price),
+		quoteLimit: num.Float(payload.QuoteQuantity),
+	}
+
+	if payload.TriggerPrice != "" {
+		bid, ask := depth.Best()
+		o.triggerRef = (bid + ask) / 2
+		o.status = client.StatusTriggerPending
+
+		if err := e.lock(market, o); err != nil {
+			return nil, rejected(err)
+		}
+
+		e.orders[o.id] = o
+
+		return o.view(), nil
+	}
+
+	if err := e.submit(market, depth, o); err != nil {
+		return nil, rejected(err)
+	}
+
+	return o.view(), nil
+}
+
+// rejected marks error as rejection before execution, like 4xx of live API
+func rejected(err error) error {
+	return fmt.Errorf("%w: %w", client.ErrInvalidOrder, err)
+}
+
+// submit matches new order against book and rests the rest if allowed
+func (e *Exchange) submit(market *client.Market, depth *client.Depth, o *order) error {
+	levels := e.levels(o.payload.Symbol, depth, o.payload.Side)
+	limit := o.payload.OrderType == string(client.OrderTypeLimit)
+
+	if limit && o.payload.PostOnly && len(levels) > 0 && crosses(o, levels[0].price) {
+		return ErrWouldCross
+	}
+
+	if o.quoteLimit > 0 {
+		// market by quote, size is known after walking book
+		o.quantity = sizeForQuote(levels, o.quoteLimit)
+	}
+
+	if o.payload.TimeInForce == string(client.TimeInForceFOK) && fillable(o, levels) < o.quantity {
+		return ErrNoLiquidity
+	}
+
+	if !limit && fillable(o, levels) == 0 {
+		return ErrNoLiquidity
+	}
+
+	if !limit && o.quoteLimit == 0 && o.payload.Side == string(client.SideBid) {
+		// market bid by base quantity pays on fill, check estimated cost only
+		if cost := costFor(levels, o.quantity); e.balance(market.QuoteSymbol).available < cost {
+			return fmt.Errorf("%w: %s need %v", ErrInsufficientFunds, market.QuoteSymbol, cost)
+		}
+	}
+
+	if err := e.lock(market, o); err != nil {
+		return err
+	}
+
+	e.orders[o.id] = o
+
+	for _, level := range levels {
+		if o.remaining() <= 0 || limit && !crosses(o, level.price) {
+			break
+		}
+
+		size := math.Min(level.size, o.remaining())
+
+		e.fill(market, o, level.price, size, false)
+		e.take(o.payload.Symbol, o.payload.Side, level.price, size)
+	}
+
+	rests := limit && o.payload.TimeInForce != string(client.TimeInForceIOC) && o.payload.TimeInForce != string(client.TimeInForceFOK)
+
+	if o.remaining() > 0 && !rests {
+		e.close(market, o, client.StatusCancelled)
+	}
+
+	return nil
+}
+
+// OnDepth matches resting orders of symbol against depth snapshot, e.g. from stream
+func (e *Exchange) OnDepth(symbol string, depth *client.Depth) error {
+	market, err := e.meta.Market(symbol)
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.prune(symbol, depth)
+
+	bid, ask := depth.Best()
+	mid := (bid + ask) / 2
+
+	for _, o := range e.sortedOpen(symbol) {
+		if o.status == client.StatusTriggerPending {
+			if !triggered(o, mid) {
+				continue
+			}
+
+			o.status = client.StatusNew
+			e.unlock(market, o)
+
+			if err := e.submit(market, depth, o); err != nil {
+				delete(e.orders, o.id)
+				o.status = client.StatusCancelled
+				e.closed = append(e.closed, o)
+			}
+
+			continue
+		}
+
+		for _, level := range e.levels(symbol, depth, o.payload.Side) {
+			if o.remaining() <= 0 || !crosses(o, level.price) {
+				break
+			}
+
+			size := level.size
+			if e.cfg.FillRatio > 0 {
+				size *= e.cfg.FillRatio
+			}
+
+			size = math.Min(size, o.remaining())
+
+			// resting order is filled at its own price as maker
+			e.fill(market, o, o.price, size, true)
+			e.take(symbol, o.payload.Side, level.price, size)
+		}
+	}
+
+	return nil
+}
+
+// Run polls depth of symbols with open orders until ctx is done
+func (e *Exchange) Run(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		interval = DefaultBookInterval
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+
+		e.mu.Lock()
+		symbols := make(map[string]bool)
+		for _, o := range e.orders {
+			symbols[o.payload.Symbol] = true
+		}
+		e.mu.Unlock()
+
+		for symbol := range symbols {
+			depth, err := e.markets.Depth(symbol)
+			if err != nil {
+				continue
+			}
+
+			e.OnDepth(symbol, depth)
+		}
+	}
+}
+
+// CancelOrder implements client.Orders.
+func (e *Exchange) CancelOrder(payload client.CancelOrderPayload) (client.Order, error) {
+	market, err := e.meta.Market(payload.Symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	o := e.find(payload.ClientID, payload.OrderID)
+	if o == nil || o.payload.Symbol != payload.Symbol {
+		return nil, &client.APIError{StatusCode: 404, Body: ErrNotFound.Error()}
+	}
+
+	e.close(market, o, client.StatusCancelled)
+
+	return o.view(), nil
+}
+
+// CancelOrders implements client.Orders.
+func (e *Exchange) CancelOrders(payload client.CancelOrderPayload) ([]client.Order, error) {
+	market, err := e.meta.Market(payload.Symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	cancelled := make([]client.Order, 0)
+
+	for _, o := range e.sortedOpen(payload.Symbol) {
+		e.close(market, o, client.StatusCancelled)
+		cancelled = append(cancelled, o.view())
+	}
+
+	return cancelled, nil
+}
+
+// ExecuteOrders implements client.BatchOrders.
+func (e *Exchange) ExecuteOrders(payloads []client.ExecuteOrderPayload) ([]client.BatchResult, error) {
+	results := make([]client.BatchResult, len(payloads))
+
+	for i, payload := range payloads {
+		order, err := e.ExecuteOrder(payload)
+
+		results[i] = client.BatchResult{Index: i, Order: order, Err: err}
+		if order != nil {
+			results[i].OrderID = order.Base().ID
+		}
+	}
+
+	return results, nil
+}
+
+// CancelOrdersByID implements client.BatchOrders.
+func (e *Exchange) CancelOrdersByID(symbol string, orderIds []string) ([]client.BatchResult, error) {
+	results := make([]client.BatchResult, len(orderIds))
+
+	for i, id := range orderIds {
+		order, err := e.CancelOrder(client.CancelOrderPayload{OrderID: id, Symbol: symbol})
+
+		results[i] = client.BatchResult{Index: i, OrderID: id, Order: order, Err: err}
+	}
+
+	return results, nil
+}
+
+// OpenOrder implements client.Orders.
+func (e *Exchange) OpenOrder(clientId uint32, orderId string, symbol string) (client.Order, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	o := e.find(clientId, orderId)
+	if o == nil || o.payload.Symbol != symbol {
+		return nil, &client.APIError{StatusCode: 404, Body: ErrNotFound.Error()}
+	}
+
+	return o.view(), nil
+}
+
+// OpenOrders implements client.Orders.
+func (e *Exchange) OpenOrders(symbol string) ([]client.Order, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	orders := make([]client.Order, 0)
+	for _, o := range e.sortedOpen(symbol) {
+		orders = append(orders, o.view())
+	}
+
+	return orders, nil
+}
+
+func (e *Exchange) find(clientId uint32, orderId string) *order {
+	for _, o := range e.orders {
+		if orderId != "" && o.id == orderId || orderId == "" && clientId != 0 && o.payload.ClientID == clientId {
+			return o
+		}
+	}
+
+	return nil
+}
+
+func (e *Exchange) sortedOpen(symbol string) []*order {
+	orders := make([]*order, 0)
+	for _, o := range e.orders {
+		if symbol == "" || o.payload.Symbol == symbol {
+			orders = append(orders, o)
+		}
+	}
+
+	sort.Slice(orders, func(i, j int) bool { return orders[i].created.Before(orders[j].created) })
+
+	return orders
+}
+
+// lock reserves funds for whole order, market orders reserve by best estimate at submit
+func (e *Exchange) lock(market *client.Market, o *order) error {
+	asset, amount := market.BaseSymbol, o.remaining()
+
+	if o.payload.Side == string(client.SideBid) {
+		asset = market.QuoteSymbol
+		amount = o.remaining() * o.price
+
+		if o.quoteLimit > 0 {
+			amount = o.quoteLimit - o.executedQuote
+		}
+	}
+
+	b := e.balance(asset)
+
+	// market bid by base quantity locks on fill, price is unknown here
+	if amount == 0 && o.payload.Side == string(client.SideBid) {
+		return nil
+	}
+
+	if b.available+1e-12 < amount {
+		return fmt.Errorf("%w: %s available %v, need %v", ErrInsufficientFunds, asset, b.available, amount)
+	}
+
+	b.available -= amount
+	b.locked += amount
+	o.locked = amount
+
+	return nil
+}
+
+func (e *Exchange) unlock(market *client.Market, o *order) {
+	asset := market.BaseSymbol
+	if o.payload.Side == string(client.SideBid) {
+		asset = market.QuoteSymbol
+	}
+
+	b := e.balance(asset)
+	b.locked -= o.locked
+	b.available += o.locked
+	o.locked = 0
+}
+
+func (e *Exchange) fill(market *client.Market, o *order, price, size float64, maker bool) {
+	if size <= 0 {
+		return
+	}
+
+	base, quote := e.balance(market.BaseSymbol), e.balance(market.QuoteSymbol)
+	notional := price * size
+
+	rate := e.cfg.TakerFee
+	if maker {
+		rate = e.cfg.MakerFee
+	}
+
+	var fee float64
+	var feeSymbol string
+
+	if o.payload.Side == string(client.SideBid) {
+		// pay quote from lock or from available for unlocked market order
+		paid := math.Min(notional, o.locked)
+		o.locked -= paid
+		quote.locked -= paid
+		quote.available -= notional - paid
+
+		fee, feeSymbol = size*rate, market.BaseSymbol
+		base.available += size - fee
+	} else {
+		o.locked -= size
+		base.locked -= size
+
+		fee, feeSymbol = notional*rate, market.QuoteSymbol
+		quote.available += notional - fee
+	}
+
+	o.executed += size
+	o.executedQuote += notional
+
+	e.fills = append(e.fills, client.Fill{
+		TradeID:   len(e.fills) + 1,
+		OrderID:   o.id,
+		Symbol:    o.payload.Symbol,
+		Side:      o.payload.Side,
+		Price:     num.String(price),
+		Quantity:  num.String(size),
+		Fee:       num.String(fee),
+		FeeSymbol: feeSymbol,
+		IsMaker:   maker,
+		Timestamp: time.Now().UTC().Format(timestampLayout),
+	})
+
+	switch {
+	case o.remaining() <= 1e-12:
+		e.close(market, o, client.StatusFilled)
+	default:
+		o.status = client.StatusPartiallyFilled
+	}
+}
+
+func (e *Exchange) close(market *client.Market, o *order, status string) {
+	e.unlock(market, o)
+
+	o.status = status
+	delete(e.orders, o.id)
+	e.closed = append(e.closed, o)
+}
+
+func (e *Exchange) balance(asset string) *balance {
+	b, ok := e.balances[asset]
+	if !ok {
+		b = &balance{}
+		e.balances[asset] = b
+	}
+
+	return b
+}
+
+type level struct {
+	price float64
+	size  float64
+}
+
+// levelKey is book level which order side trades against
+type levelKey struct {
+	side  string
+	price float64
+}
+
+// levels returns opposite levels of side without liquidity taken by earlier fills
+func (e *Exchange) levels(symbol string, depth *client.Depth, side string) []level {
+	taken := e.taken[symbol]
+	levels := make([]level, 0)
+
+	for _, l := range opposite(depth, side) {
+		l.size -= taken[levelKey{side, l.price}]
+		if l.size > 1e-12 {
+			levels = append(levels, l)
+		}
+	}
+
+	return levels
+}
+
+func (e *Exchange) take(symbol, side string, price, size float64) {
+	if e.taken[symbol] == nil {
+		e.taken[symbol] = make(map[levelKey]float64)
+	}
+
+	e.taken[symbol][levelKey{side, price}] += size
+}
+
+// prune forgets taken liquidity of levels missing in depth, they were refreshed by book
+func (e *Exchange) prune(symbol string, depth *client.Depth) {
+	taken := e.taken[symbol]
+	if len(taken) == 0 {
+		return
+	}
+
+	present := make(map[levelKey]bool)
+	for _, side := range []string{string(client.SideBid), string(client.SideAsk)} {
+		for _, l := range opposite(depth, side) {
+			present[levelKey{side, l.price}] = true
+		}
+	}
+
+	for key := range taken {
+		if !present[key] {
+			delete(taken, key)
+		}
+	}
+}
+
+// opposite returns levels order can trade against, best first
+func opposite(depth *client.Depth, side string) []level {
+	raw := depth.Asks
+	if side == string(client.SideAsk) {
+		raw = depth.Bids
+	}
+
+	levels := make([]level, 0, len(raw))
+	for _, l := range raw {
+		if len(l) < 2 {
+			continue
+		}
+
+		levels = append(levels, level{price: num.Float(l[0]), size: num.Float(l[1])})
+	}
+
+	sort.Slice(levels, func(i, j int) bool {
+		if side == string(client.SideAsk) {
+			return levels[i].price > levels[j].price
+		}
+
+		return levels[i].price < levels[j].price
+	})
+
+	return levels
+}
+
+func crosses(o *order, price float64) bool {
+	if o.payload.OrderType != string(client.OrderTypeLimit) {
+		return true
+	}
+
+	if o.payload.Side == string(client.SideBid) {
+		return price <= o.price
+	}
+
+	return price >= o.price
+}
+
+func fillable(o *order, levels []level) float64 {
+	total := 0.0
+	for _, l := range levels {
+		if !crosses(o, l.price) {
+			break
+		}
+
+		total += l.size
+	}
+
+	return total
+}
+
+func sizeForQuote(levels []level, quote float64) float64 {
+	size := 0.0
+
+	for _, l := range levels {
+		if quote <= 0 {
+			break
+		}
+
+		take := math.Min(l.size, quote/l.price)
+		size += take
+		quote -= take * l.price
+	}
+
+	return size
+}
+
+func costFor(levels []level, size float64) float64 {
+	cost := 0.0
+
+	for _, l := range levels {
+		if size <= 0 {
+			break
+		}
+
+		take := math.Min(l.size, size)
+		cost += take * l.price
+		size -= take
+	}
+
+	return cost
+}
+
+func triggered(o *order, mid float64) bool {
+	trigger := num.Float(o.payload.TriggerPrice)
+
+	if o.triggerRef < trigger {
+		return mid >= trigger
+	}
+
+	return mid <= trigger
+}
@@ -0,0 +1,66 @@
+package paper
+
+import (
+	"github.com/leenzstra/backpack-go/client"
+)
+
+// OrderHistory implements client.History.
+func (e *Exchange) OrderHistory(orderId string, symbol string, offset int64, limit int64) ([]client.Order, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	history := make([]client.Order, 0)
+
+	// newest first like exchange
+	for i := len(e.closed) - 1; i >= 0; i-- {
+		o := e.closed[i]
+
+		if orderId != "" && o.id != orderId || symbol != "" && o.payload.Symbol != symbol {
+			continue
+		}
+
+		history = append(history, o.view())
+	}
+
+	return page(history, offset, limit), nil
+}
+
+// FillHistory implements client.History.
+func (e *Exchange) FillHistory(orderId string, symbol string, from int64, to int64, offset int64, limit int64) ([]client.Fill, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	history := make([]client.Fill, 0)
+
+	for i := len(e.fills) - 1; i >= 0; i-- {
+		f := e.fills[i]
+
+		if orderId != "" && f.OrderID != orderId || symbol != "" && f.Symbol != symbol {
+			continue
+		}
+
+		if ts, err := f.Time(); err == nil {
+			if from > 0 && ts.UnixMilli() < from || to > 0 && ts.UnixMilli() > to {
+				continue
+			}
+		}
+
+		history = append(history, f)
+	}
+
+	return page(history, offset, limit), nil
+}
+
+func page[T any](items []T, offset, limit int64) []T {
+	if offset >= int64(len(items)) {
+		return items[:0]
+	}
+
+	items = items[offset:]
+
+	if limit > 0 && limit < int64(len(items)) {
+		items = items[:limit]
+	}
+
+	return items
+}
@@ -0,0 +1,215 @@
+package portfolio
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/leenzstra/backpack-go/client"
+	"github.com/leenzstra/backpack-go/internal/num"
+)
+
+// SymbolFees is fee breakdown of one market, amounts are in report quote
+type SymbolFees struct {
+	Symbol      string
+	MakerFills  int
+	TakerFills  int
+	MakerVolume float64
+	TakerVolume float64
+	MakerFees   float64
+	TakerFees   float64
+}
+
+func (s SymbolFees) Volume() float64 {
+	return s.MakerVolume + s.TakerVolume
+}
+
+func (s SymbolFees) Fees() float64 {
+	return s.MakerFees + s.TakerFees
+}
+
+// MakerRatio is maker share of volume
+func (s SymbolFees) MakerRatio() float64 {
+	if s.Volume() == 0 {
+		return 0
+	}
+
+	return s.MakerVolume / s.Volume()
+}
+
+// EffectiveRate is fees paid per unit of volume
+func (s SymbolFees) EffectiveRate() float64 {
+	if s.Volume() == 0 {
+		return 0
+	}
+
+	return s.Fees() / s.Volume()
+}
+
+func (s SymbolFees) MakerRate() float64 {
+	if s.MakerVolume == 0 {
+		return 0
+	}
+
+	return s.MakerFees / s.MakerVolume
+}
+
+func (s SymbolFees) TakerRate() float64 {
+	if s.TakerVolume == 0 {
+		return 0
+	}
+
+	return s.TakerFees / s.TakerVolume
+}
+
+type FeeReport struct {
+	From  time.Time
+	To    time.Time
+	Quote string
+	// Raw fees per asset
+	Fees map[string]float64
+	// Fees per asset converted to quote at fill time
+	QuoteFees map[string]float64
+	Total     float64
+	Symbols   map[string]*SymbolFees
+	// Fees per asset which could not be priced, not included in totals
+	Unpriced map[string]float64
+	// Estimated quote saved if taker volume was filled as maker
+	Savings float64
+}
+
+// SortedSymbols returns symbols by fees paid, largest first
+func (r *FeeReport) SortedSymbols() []*SymbolFees {
+	symbols := make([]*SymbolFees, 0, len(r.Symbols))
+	for _, s := range r.Symbols {
+		symbols = append(symbols, s)
+	}
+
+	sort.Slice(symbols, func(i, j int) bool { return symbols[i].Fees() > symbols[j].Fees() })
+
+	return symbols
+}
+
+// FeeAnalyzer aggregates fees from fill history
+type FeeAnalyzer struct {
+	// Maker fee rate used for savings of symbols without maker fills
+	MakerRate float64
+
+	history client.History
+	prices  *HistoricalPrices
+}
+
+func NewFeeAnalyzer(history client.History, markets client.Markets) *FeeAnalyzer {
+	return &FeeAnalyzer{history: history, prices: NewHistoricalPrices(markets)}
+}
+
+// Report loads fills of symbol between from and to and converts them to quote.
+// Empty symbol reports all markets
+func (a *FeeAnalyzer) Report(symbol, quote string, from, to time.Time) (*FeeReport, error) {
+	fills, err := a.fills(symbol, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return a.Aggregate(fills, quote, from, to)
+}
+
+// Aggregate builds report from already loaded fills
+func (a *FeeAnalyzer) Aggregate(fills []client.Fill, quote string, from, to time.Time) (*FeeReport, error) {
+	r := &FeeReport{
+		From:      from,
+		To:        to,
+		Quote:     quote,
+		Fees:      make(map[string]float64),
+		QuoteFees: make(map[string]float64),
+		Symbols:   make(map[string]*SymbolFees),
+		Unpriced:  make(map[string]float64),
+	}
+
+	for _, f := range fills {
+		t, err := f.Time()
+		if err != nil {
+			return nil, fmt.Errorf("fill %d timestamp err: %v", f.TradeID, err)
+		}
+
+		base, marketQuote, err := a.prices.Assets(f.Symbol)
+		if err != nil {
+			return nil, err
+		}
+
+		price := num.Float(f.Price)
+		fee := num.Float(f.Fee)
+
+		s, ok := r.Symbols[f.Symbol]
+		if !ok {
+			s = &SymbolFees{Symbol: f.Symbol}
+			r.Symbols[f.Symbol] = s
+		}
+
+		r.Fees[f.FeeSymbol] += fee
+
+		// market quote price in report quote, fill itself prices base
+		toQuote, err := a.prices.Price(marketQuote, quote, t)
+		if err != nil {
+			r.Unpriced[f.FeeSymbol] += fee
+			continue
+		}
+
+		volume := price * num.Float(f.Quantity) * toQuote
+
+		var feeQuote float64
+		switch f.FeeSymbol {
+		case base:
+			feeQuote = fee * price * toQuote
+		case marketQuote:
+			feeQuote = fee * toQuote
+		default:
+			rate, err := a.prices.Price(f.FeeSymbol, quote, t)
+			if err != nil {
+				r.Unpriced[f.FeeSymbol] += fee
+				continue
+			}
+
+			feeQuote = fee * rate
+		}
+
+		r.QuoteFees[f.FeeSymbol] += feeQuote
+		r.Total += feeQuote
+
+		if f.IsMaker {
+			s.MakerFills++
+			s.MakerVolume += volume
+			s.MakerFees += feeQuote
+		} else {
+			s.TakerFills++
+			s.TakerVolume += volume
+			s.TakerFees += feeQuote
+		}
+	}
+
+	for _, s := range r.Symbols {
+		makerRate := a.MakerRate
+		if s.MakerVolume > 0 {
+			makerRate = s.MakerRate()
+		}
+
+		if saved := s.TakerFees - s.TakerVolume*makerRate; saved > 0 {
+			r.Savings += saved
+		}
+	}
+
+	return r, nil
+}
+
+func (a *FeeAnalyzer) fills(symbol string, from, to time.Time) ([]client.Fill, error) {
+	pager := client.NewPager(client.FillHistoryPage(a.history, "", symbol, from, to))
+	pager.Prefetch = true
+
+	fills, err := client.Collect(context.Background(), pager)
+	if err != nil {
+		return nil, fmt.Errorf("fill history err: %v", err)
+	}
+
+	return fills, nil
+}
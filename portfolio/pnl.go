@@ -0,0 +1,404 @@
+package portfolio
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/leenzstra/backpack-go/client"
+	"github.com/leenzstra/backpack-go/internal/num"
+)
+
+type Method int
+
+const (
+	FIFO Method = iota
+	LIFO
+	AverageCost
+)
+
+func (m Method) String() string {
+	switch m {
+	case FIFO:
+		return "fifo"
+	case LIFO:
+		return "lifo"
+	case AverageCost:
+		return "average"
+	}
+
+	return fmt.Sprintf("method(%d)", int(m))
+}
+
+type MarkSource int
+
+const (
+	MarkLast MarkSource = iota
+	MarkMid
+)
+
+// Lot is open part of fill, negative quantity is short
+type Lot struct {
+	Quantity float64
+	Price    float64
+	Time     time.Time
+}
+
+type Position struct {
+	Symbol string
+	// Signed base quantity, negative is short
+	Quantity     float64
+	AverageEntry float64
+	Realized     float64
+	Unrealized   float64
+	MarkPrice    float64
+	// Fees paid per asset
+	Fees   map[string]float64
+	Volume float64
+	Trades int
+	Lots   []Lot
+}
+
+func (p Position) TotalPnL() float64 {
+	return p.Realized + p.Unrealized
+}
+
+func (p *Position) clone() Position {
+	c := *p
+	c.Lots = append([]Lot{}, p.Lots...)
+	c.Fees = make(map[string]float64, len(p.Fees))
+
+	for asset, fee := range p.Fees {
+		c.Fees[asset] = fee
+	}
+
+	return c
+}
+
+type Snapshot struct {
+	Time       time.Time
+	Method     Method
+	Positions  map[string]Position
+	Realized   float64
+	Unrealized float64
+}
+
+// Point is PnL of symbol at time of fill or mark
+type Point struct {
+	Time       time.Time
+	Symbol     string
+	Quantity   float64
+	Realized   float64
+	Unrealized float64
+	MarkPrice  float64
+}
+
+// PnL keeps positions and realized PnL built from fills.
+// Realized PnL is gross, fees are reported separately per asset
+type PnL struct {
+	method Method
+
+	mu        sync.Mutex
+	positions map[string]*Position
+	seen      map[int]bool
+	series    []Point
+	// Time of last ingested fill by symbol
+	last map[string]time.Time
+}
+
+func NewPnL(method Method) *PnL {
+	return &PnL{
+		method:    method,
+		positions: make(map[string]*Position),
+		seen:      make(map[int]bool),
+		last:      make(map[string]time.Time),
+	}
+}
+
+// Ingest applies fills in time order, already seen trades are skipped
+func (p *PnL) Ingest(fills ...client.Fill) error {
+	type timed struct {
+		fill client.Fill
+		time time.Time
+	}
+
+	sorted := make([]timed, 0, len(fills))
+	for _, f := range fills {
+		t, err := f.Time()
+		if err != nil {
+			return fmt.Errorf("fill %d timestamp err: %v", f.TradeID, err)
+		}
+
+		sorted = append(sorted, timed{f, t})
+	}
+
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].time.Before(sorted[j].time) })
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, f := range sorted {
+		if p.seen[f.fill.TradeID] {
+			continue
+		}
+
+		p.seen[f.fill.TradeID] = true
+		p.apply(f.fill, f.time)
+
+		if f.time.After(p.last[f.fill.Symbol]) {
+			p.last[f.fill.Symbol] = f.time
+		}
+	}
+
+	return nil
+}
+
+// Backfill loads fills of symbol between from and to, zero times are not limited
+func (p *PnL) Backfill(history client.History, symbol string, from, to time.Time) error {
+	fills, err := client.Collect(context.Background(), client.NewPager(client.FillHistoryPage(history, "", symbol, from, to)))
+	if err != nil {
+		return fmt.Errorf("fill history err: %v", err)
+	}
+
+	return p.Ingest(fills...)
+}
+
+// Sync loads fills since last ingested one of symbol, it is used for live updates by polling.
+// Empty symbol loads since earliest last fill of all symbols
+func (p *PnL) Sync(history client.History, symbol string) error {
+	p.mu.Lock()
+	from := p.last[symbol]
+	if symbol == "" {
+		for _, t := range p.last {
+			if from.IsZero() || t.Before(from) {
+				from = t
+			}
+		}
+	}
+	p.mu.Unlock()
+
+	return p.Backfill(history, symbol, from, time.Time{})
+}
+
+// SetMark updates mark price of symbol, e.g. from stream
+func (p *PnL) SetMark(symbol string, price float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pos, ok := p.positions[symbol]
+	if !ok || price <= 0 {
+		return
+	}
+
+	pos.MarkPrice = price
+	pos.Unrealized = unrealized(pos)
+
+	p.point(pos, time.Now())
+}
+
+// Mark updates mark prices of all positions from tickers or book mid
+func (p *PnL) Mark(markets client.Markets, source MarkSource) error {
+	p.mu.Lock()
+	symbols := make([]string, 0, len(p.positions))
+	for symbol := range p.positions {
+		symbols = append(symbols, symbol)
+	}
+	p.mu.Unlock()
+
+	prices := make(map[string]float64, len(symbols))
+
+	switch source {
+	case MarkMid:
+		for _, symbol := range symbols {
+			depth, err := markets.Depth(symbol)
+			if err != nil {
+				return fmt.Errorf("depth %s err: %v", symbol, err)
+			}
+
+			if bid, ask := depth.Best(); bid > 0 && ask > 0 {
+				prices[symbol] = (bid + ask) / 2
+			}
+		}
+	default:
+		tickers, err := markets.Tickers()
+		if err != nil {
+			return fmt.Errorf("tickers err: %v", err)
+		}
+
+		for _, t := range tickers {
+			prices[t.Symbol] = num.Float(t.LastPrice)
+		}
+	}
+
+	for _, symbol := range symbols {
+		p.SetMark(symbol, prices[symbol])
+	}
+
+	return nil
+}
+
+func (p *PnL) Position(symbol string) (Position, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pos, ok := p.positions[symbol]
+	if !ok {
+		return Position{}, false
+	}
+
+	return pos.clone(), true
+}
+
+func (p *PnL) Snapshot() Snapshot {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	s := Snapshot{Time: time.Now(), Method: p.method, Positions: make(map[string]Position, len(p.positions))}
+
+	for symbol, pos := range p.positions {
+		s.Positions[symbol] = pos.clone()
+		s.Realized += pos.Realized
+		s.Unrealized += pos.Unrealized
+	}
+
+	return s
+}
+
+// Series returns points of symbol in time order, empty symbol returns all
+func (p *PnL) Series(symbol string) []Point {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	series := make([]Point, 0)
+	for _, point := range p.series {
+		if symbol == "" || point.Symbol == symbol {
+			series = append(series, point)
+		}
+	}
+
+	return series
+}
+
+func (p *PnL) apply(f client.Fill, t time.Time) {
+	pos, ok := p.positions[f.Symbol]
+	if !ok {
+		pos = &Position{Symbol: f.Symbol, Fees: make(map[string]float64)}
+		p.positions[f.Symbol] = pos
+	}
+
+	price := num.Float(f.Price)
+	quantity := num.Float(f.Quantity)
+	fee := num.Float(f.Fee)
+
+	pos.Trades++
+	pos.Volume += price * quantity
+
+	if fee != 0 {
+		pos.Fees[f.FeeSymbol] += fee
+	}
+
+	signed := quantity
+	if f.Side == string(client.SideAsk) {
+		signed = -quantity
+	}
+
+	// fee taken from received base reduces position
+	if base, _, _ := strings.Cut(f.Symbol, "_"); f.FeeSymbol == base && signed > 0 {
+		signed -= fee
+	}
+
+	p.trade(pos, signed, price, t)
+
+	pos.AverageEntry = average(pos.Lots)
+	if pos.MarkPrice == 0 {
+		pos.MarkPrice = price
+	}
+	pos.Unrealized = unrealized(pos)
+
+	p.point(pos, t)
+}
+
+// trade closes lots against signed quantity and opens lot with the rest
+func (p *PnL) trade(pos *Position, signed, price float64, t time.Time) {
+	for signed != 0 && len(pos.Lots) > 0 && sign(pos.Lots[0].Quantity) != sign(signed) {
+		i := 0
+		if p.method == LIFO {
+			i = len(pos.Lots) - 1
+		}
+
+		lot := &pos.Lots[i]
+		closed := math.Min(math.Abs(lot.Quantity), math.Abs(signed))
+
+		pos.Realized += closed * (price - lot.Price) * sign(lot.Quantity)
+
+		lot.Quantity -= closed * sign(lot.Quantity)
+		signed -= closed * sign(signed)
+		pos.Quantity -= closed * sign(pos.Quantity)
+
+		if math.Abs(lot.Quantity) < 1e-12 {
+			pos.Lots = append(pos.Lots[:i], pos.Lots[i+1:]...)
+		}
+	}
+
+	if math.Abs(signed) < 1e-12 {
+		return
+	}
+
+	pos.Quantity += signed
+	pos.Lots = append(pos.Lots, Lot{Quantity: signed, Price: price, Time: t})
+
+	// average cost keeps single lot
+	if p.method == AverageCost {
+		pos.Lots = []Lot{{Quantity: pos.Quantity, Price: average(pos.Lots), Time: pos.Lots[0].Time}}
+	}
+}
+
+func (p *PnL) point(pos *Position, t time.Time) {
+	p.series = append(p.series, Point{
+		Time:       t,
+		Symbol:     pos.Symbol,
+		Quantity:   pos.Quantity,
+		Realized:   pos.Realized,
+		Unrealized: pos.Unrealized,
+		MarkPrice:  pos.MarkPrice,
+	})
+}
+
+func average(lots []Lot) float64 {
+	var quantity, cost float64
+	for _, lot := range lots {
+		quantity += lot.Quantity
+		cost += lot.Quantity * lot.Price
+	}
+
+	if quantity == 0 {
+		return 0
+	}
+
+	return cost / quantity
+}
+
+func unrealized(pos *Position) float64 {
+	if pos.MarkPrice == 0 {
+		return 0
+	}
+
+	total := 0.0
+	for _, lot := range pos.Lots {
+		total += lot.Quantity * (pos.MarkPrice - lot.Price)
+	}
+
+	return total
+}
+
+func sign(f float64) float64 {
+	if f < 0 {
+		return -1
+	}
+
+	return 1
+}
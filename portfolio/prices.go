@@ -0,0 +1,206 @@
+package portfolio
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/leenzstra/backpack-go/client"
+	"github.com/leenzstra/backpack-go/internal/num"
+)
+
+// hop is market from asset to other one, inverse if asset is its quote
+type hop struct {
+	to      string
+	symbol  string
+	inverse bool
+}
+
+// router finds conversions between assets through markets
+type router struct {
+	hops map[string][]hop
+	// Base and quote by market symbol
+	assets map[string][2]string
+}
+
+func newRouter(markets []client.Market) *router {
+	r := &router{hops: make(map[string][]hop), assets: make(map[string][2]string, len(markets))}
+
+	for _, m := range markets {
+		r.assets[m.Symbol] = [2]string{m.BaseSymbol, m.QuoteSymbol}
+		r.hops[m.BaseSymbol] = append(r.hops[m.BaseSymbol], hop{to: m.QuoteSymbol, symbol: m.Symbol})
+		r.hops[m.QuoteSymbol] = append(r.hops[m.QuoteSymbol], hop{to: m.BaseSymbol, symbol: m.Symbol, inverse: true})
+	}
+
+	return r
+}
+
+// convert finds route with fewest hops from asset to quote, markets without price are skipped.
+// Price is quote per base of market symbol, maxHops of zero is not limited
+func (r *router) convert(asset, quote string, maxHops int, price func(symbol string) (float64, bool)) (float64, []string, bool) {
+	if asset == quote {
+		return 1, nil, true
+	}
+
+	rate := func(h hop) (float64, bool) {
+		p, ok := price(h.symbol)
+		if !ok || p <= 0 {
+			return 0, false
+		}
+
+		if h.inverse {
+			return 1 / p, true
+		}
+
+		return p, true
+	}
+
+	type step struct {
+		asset string
+		rate  float64
+		route []string
+	}
+
+	visited := map[string]bool{asset: true}
+	queue := []step{{asset: asset, rate: 1, route: []string{asset}}}
+
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+
+		depth := len(cur.route) - 1
+
+		// direct market first, prices may be fetched per market
+		for _, h := range r.hops[cur.asset] {
+			if h.to != quote {
+				continue
+			}
+
+			if p, ok := rate(h); ok {
+				return cur.rate * p, append(append([]string{}, cur.route...), quote), true
+			}
+		}
+
+		if maxHops > 0 && depth+1 >= maxHops {
+			continue
+		}
+
+		for _, h := range r.hops[cur.asset] {
+			if visited[h.to] || h.to == quote {
+				continue
+			}
+
+			p, ok := rate(h)
+			if !ok {
+				continue
+			}
+
+			visited[h.to] = true
+			queue = append(queue, step{asset: h.to, rate: cur.rate * p, route: append(append([]string{}, cur.route...), h.to)})
+		}
+	}
+
+	return 0, nil, false
+}
+
+// Hops of route used by HistoricalPrices
+const historicalMaxHops = 2
+
+// HistoricalPrices converts assets at past time by close of 1m KLines.
+// Conversion goes through direct, inverse or one intermediate market
+type HistoricalPrices struct {
+	markets client.Markets
+
+	mu     sync.Mutex
+	router *router
+	cache  map[string]float64
+}
+
+func NewHistoricalPrices(markets client.Markets) *HistoricalPrices {
+	return &HistoricalPrices{markets: markets, cache: make(map[string]float64)}
+}
+
+// Price returns amount of quote for one unit of asset at t
+func (h *HistoricalPrices) Price(asset, quote string, t time.Time) (float64, error) {
+	if asset == quote {
+		return 1, nil
+	}
+
+	r, err := h.load()
+	if err != nil {
+		return 0, err
+	}
+
+	price, _, ok := r.convert(asset, quote, historicalMaxHops, func(symbol string) (float64, bool) {
+		price, err := h.close(symbol, t)
+		return price, err == nil
+	})
+	if !ok {
+		return 0, fmt.Errorf("no price for %s in %s at %s", asset, quote, t.Format(time.RFC3339))
+	}
+
+	return price, nil
+}
+
+func (h *HistoricalPrices) load() (*router, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.router != nil {
+		return h.router, nil
+	}
+
+	markets, err := h.markets.Markets()
+	if err != nil {
+		return nil, fmt.Errorf("markets err: %v", err)
+	}
+
+	h.router = newRouter(markets)
+
+	return h.router, nil
+}
+
+// Assets returns base and quote of market symbol
+func (h *HistoricalPrices) Assets(symbol string) (base, quote string, err error) {
+	r, err := h.load()
+	if err != nil {
+		return "", "", err
+	}
+
+	assets, ok := r.assets[symbol]
+	if !ok {
+		return "", "", fmt.Errorf("unknown market %s", symbol)
+	}
+
+	return assets[0], assets[1], nil
+}
+
+func (h *HistoricalPrices) close(symbol string, t time.Time) (float64, error) {
+	start := t.UTC().Truncate(time.Minute)
+	key := fmt.Sprintf("%s@%d", symbol, start.Unix())
+
+	h.mu.Lock()
+	price, ok := h.cache[key]
+	h.mu.Unlock()
+
+	if ok {
+		return price, nil
+	}
+
+	klines, err := h.markets.KLines(symbol, client.Interval1m, start, start.Add(time.Minute))
+	if err != nil {
+		return 0, err
+	}
+
+	if len(klines) == 0 {
+		return 0, fmt.Errorf("no klines for %s at %s", symbol, start.Format(time.RFC3339))
+	}
+
+	price = num.Float(klines[len(klines)-1].Close)
+
+	h.mu.Lock()
+	h.cache[key] = price
+	h.mu.Unlock()
+
+	return price, nil
+}
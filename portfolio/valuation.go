@@ -0,0 +1,376 @@
+package portfolio
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/leenzstra/backpack-go/client"
+	"github.com/leenzstra/backpack-go/internal/jsonl"
+	"github.com/leenzstra/backpack-go/internal/num"
+)
+
+const DefaultSnapshotInterval = time.Hour
+
+type AssetValue struct {
+	Asset     string  `json:"asset"`
+	Available float64 `json:"available"`
+	Locked    float64 `json:"locked"`
+	Staked    float64 `json:"staked"`
+	Total     float64 `json:"total"`
+	// Quote per unit of asset, zero if not priced
+	Price float64 `json:"price"`
+	Value float64 `json:"value"`
+	// Assets price was routed through, from asset to quote
+	Route []string `json:"route,omitempty"`
+}
+
+type Valuation struct {
+	Time   time.Time    `json:"time"`
+	Quote  string       `json:"quote"`
+	Equity float64      `json:"equity"`
+	Assets []AssetValue `json:"assets"`
+	// Assets without route to quote, not included in equity
+	Unpriced []string `json:"unpriced,omitempty"`
+}
+
+// Allocation returns share of equity by asset
+func (v Valuation) Allocation() map[string]float64 {
+	allocation := make(map[string]float64, len(v.Assets))
+	if v.Equity == 0 {
+		return allocation
+	}
+
+	for _, a := range v.Assets {
+		allocation[a.Asset] = a.Value / v.Equity
+	}
+
+	return allocation
+}
+
+func (v Valuation) value(asset string) float64 {
+	for _, a := range v.Assets {
+		if a.Asset == asset {
+			return a.Value
+		}
+	}
+
+	return 0
+}
+
+// Valuator prices balances in quote asset by last prices of tickers
+type Valuator struct {
+	markets client.Markets
+	capital client.Capital
+
+	mu     sync.Mutex
+	router *router
+}
+
+func NewValuator(markets client.Markets, capital client.Capital) *Valuator {
+	return &Valuator{markets: markets, capital: capital}
+}
+
+// Value prices all balances, assets without direct market are routed through intermediate ones
+func (v *Valuator) Value(quote string) (Valuation, error) {
+	balances, err := v.capital.Balances()
+	if err != nil {
+		return Valuation{}, fmt.Errorf("balances err: %v", err)
+	}
+
+	r, err := v.load()
+	if err != nil {
+		return Valuation{}, err
+	}
+
+	tickers, err := v.markets.Tickers()
+	if err != nil {
+		return Valuation{}, fmt.Errorf("tickers err: %v", err)
+	}
+
+	prices := make(map[string]float64, len(tickers))
+	for _, t := range tickers {
+		prices[t.Symbol] = num.Float(t.LastPrice)
+	}
+
+	last := func(symbol string) (float64, bool) {
+		price, ok := prices[symbol]
+		return price, ok
+	}
+
+	val := Valuation{Time: time.Now(), Quote: quote}
+
+	for asset, b := range balances {
+		a := AssetValue{
+			Asset:     asset,
+			Available: num.Float(b.Available),
+			Locked:    num.Float(b.Locked),
+			Staked:    num.Float(b.Staked),
+		}
+		a.Total = a.Available + a.Locked + a.Staked
+
+		if a.Total == 0 {
+			continue
+		}
+
+		price, route, ok := r.convert(asset, quote, 0, last)
+		if !ok {
+			val.Unpriced = append(val.Unpriced, asset)
+			val.Assets = append(val.Assets, a)
+			continue
+		}
+
+		a.Price = price
+		a.Value = a.Total * price
+		a.Route = route
+
+		val.Equity += a.Value
+		val.Assets = append(val.Assets, a)
+	}
+
+	sort.Slice(val.Assets, func(i, j int) bool { return val.Assets[i].Value > val.Assets[j].Value })
+	sort.Strings(val.Unpriced)
+
+	return val, nil
+}
+
+func (v *Valuator) load() (*router, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.router != nil {
+		return v.router, nil
+	}
+
+	markets, err := v.markets.Markets()
+	if err != nil {
+		return nil, fmt.Errorf("markets err: %v", err)
+	}
+
+	v.router = newRouter(markets)
+
+	return v.router, nil
+}
+
+// SnapshotStore keeps valuations in time order
+type SnapshotStore interface {
+	Append(v Valuation) error
+	// Range returns snapshots within from and to, zero times are not limited
+	Range(from, to time.Time) ([]Valuation, error)
+}
+
+// MemorySnapshotStore keeps snapshots in process only
+type MemorySnapshotStore struct {
+	mu        sync.Mutex
+	snapshots []Valuation
+}
+
+func (s *MemorySnapshotStore) Append(v Valuation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.snapshots = append(s.snapshots, v)
+	return nil
+}
+
+func (s *MemorySnapshotStore) Range(from, to time.Time) ([]Valuation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return filter(s.snapshots, from, to), nil
+}
+
+// FileSnapshotStore appends snapshots to file as JSON lines
+type FileSnapshotStore struct {
+	Path string
+
+	mu sync.Mutex
+}
+
+func (s *FileSnapshotStore) Append(v Valuation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return jsonl.Append(s.Path, v)
+}
+
+func (s *FileSnapshotStore) Range(from, to time.Time) ([]Valuation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.Open(s.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return []Valuation{}, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	snapshots := make([]Valuation, 0)
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	for scanner.Scan() {
+		var v Valuation
+		if err := json.Unmarshal(scanner.Bytes(), &v); err != nil {
+			return nil, fmt.Errorf("snapshot line err: %v", err)
+		}
+
+		snapshots = append(snapshots, v)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return filter(snapshots, from, to), nil
+}
+
+func filter(snapshots []Valuation, from, to time.Time) []Valuation {
+	filtered := make([]Valuation, 0)
+	for _, v := range snapshots {
+		if !from.IsZero() && v.Time.Before(from) || !to.IsZero() && v.Time.After(to) {
+			continue
+		}
+
+		filtered = append(filtered, v)
+	}
+
+	return filtered
+}
+
+// Snapshotter stores valuation periodically
+type Snapshotter struct {
+	Interval time.Duration
+	OnError  func(err error)
+
+	valuator *Valuator
+	store    SnapshotStore
+	quote    string
+}
+
+func NewSnapshotter(valuator *Valuator, store SnapshotStore, quote string) *Snapshotter {
+	return &Snapshotter{Interval: DefaultSnapshotInterval, valuator: valuator, store: store, quote: quote}
+}
+
+// Take values balances and stores snapshot
+func (s *Snapshotter) Take() (Valuation, error) {
+	v, err := s.valuator.Value(s.quote)
+	if err != nil {
+		return v, err
+	}
+
+	if err := s.store.Append(v); err != nil {
+		return v, fmt.Errorf("store snapshot err: %v", err)
+	}
+
+	return v, nil
+}
+
+// Run takes snapshots until ctx is done
+func (s *Snapshotter) Run(ctx context.Context) error {
+	interval := s.Interval
+	if interval <= 0 {
+		interval = DefaultSnapshotInterval
+	}
+
+	for {
+		if _, err := s.Take(); err != nil && s.OnError != nil {
+			s.OnError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+type EquityPoint struct {
+	Time   time.Time
+	Equity float64
+}
+
+// EquityCurve returns equity of stored snapshots
+func EquityCurve(store SnapshotStore, from, to time.Time) ([]EquityPoint, error) {
+	snapshots, err := store.Range(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	curve := make([]EquityPoint, len(snapshots))
+	for i, v := range snapshots {
+		curve[i] = EquityPoint{Time: v.Time, Equity: v.Equity}
+	}
+
+	return curve, nil
+}
+
+type Mover struct {
+	Asset      string
+	StartValue float64
+	EndValue   float64
+	Change     float64
+	// Relative change, zero for new asset
+	ChangePercent float64
+	// Asset was missing at start
+	New bool
+}
+
+// LargestMovers compares first and last snapshot in range and returns n assets
+// with largest absolute value change, n of zero returns all
+func LargestMovers(store SnapshotStore, from, to time.Time, n int) ([]Mover, error) {
+	snapshots, err := store.Range(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(snapshots) < 2 {
+		return []Mover{}, nil
+	}
+
+	first, last := snapshots[0], snapshots[len(snapshots)-1]
+
+	assets := make(map[string]bool)
+	for _, a := range first.Assets {
+		assets[a.Asset] = true
+	}
+	for _, a := range last.Assets {
+		assets[a.Asset] = true
+	}
+
+	movers := make([]Mover, 0, len(assets))
+
+	for asset := range assets {
+		m := Mover{Asset: asset, StartValue: first.value(asset), EndValue: last.value(asset)}
+		m.Change = m.EndValue - m.StartValue
+
+		switch {
+		case m.StartValue != 0:
+			m.ChangePercent = m.Change / m.StartValue * 100
+		case m.Change != 0:
+			m.New = true
+		}
+
+		movers = append(movers, m)
+	}
+
+	sort.Slice(movers, func(i, j int) bool { return math.Abs(movers[i].Change) > math.Abs(movers[j].Change) })
+
+	if n > 0 && n < len(movers) {
+		movers = movers[:n]
+	}
+
+	return movers, nil
+}
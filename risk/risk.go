@@ -0,0 +1,430 @@
+package risk
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/leenzstra/backpack-go/client"
+	"github.com/leenzstra/backpack-go/internal/num"
+)
+
+var (
+	_ client.Orders      = (*Guard)(nil)
+	_ client.BatchOrders = (*Guard)(nil)
+)
+
+var ErrRejected = errors.New("rejected by risk check")
+
+type Rule string
+
+const (
+	RuleAllowlist     Rule = "allowlist"
+	RuleOrderNotional Rule = "order_notional"
+	RuleOpenOrders    Rule = "open_orders"
+	RulePosition      Rule = "position"
+	RulePriceBand     Rule = "price_band"
+	RuleOrderRate     Rule = "order_rate"
+	RuleMarketData    Rule = "market_data"
+)
+
+// Limits of zero value are disabled
+type Limits struct {
+	// Symbols allowed to trade, empty allows all
+	Allowlist []string
+	// Max quote value of one order
+	MaxOrderNotional float64
+	// Max open orders per symbol
+	MaxOpenOrders int
+	// Max total balance per asset after order fill
+	MaxPosition map[string]float64
+	// Max distance of limit price from book mid
+	PriceBandBps float64
+	// Max orders sent within RateWindow
+	MaxOrders  int
+	RateWindow time.Duration
+}
+
+// Violation is structured rejection returned by Guard
+type Violation struct {
+	Rule   Rule
+	Symbol string
+	Value  float64
+	Limit  float64
+	Reason string
+}
+
+func (v *Violation) Error() string {
+	return fmt.Sprintf("risk %s %s: %s (value %v, limit %v)", v.Rule, v.Symbol, v.Reason, v.Value, v.Limit)
+}
+
+func (v *Violation) Unwrap() error {
+	return ErrRejected
+}
+
+type Event struct {
+	Time     time.Time
+	Payload  client.ExecuteOrderPayload
+	Accepted bool
+	// Nil for accepted orders
+	Violation *Violation
+}
+
+// Guard checks orders against limits before passing them to next Orders
+type Guard struct {
+	// Called for every checked order
+	OnEvent func(e Event)
+	// Optional, rejections are logged at warn level
+	Logger *slog.Logger
+
+	next    client.Orders
+	markets client.Markets
+	capital client.Capital
+	meta    *client.OrderValidator
+
+	mu     sync.Mutex
+	limits Limits
+	sent   []time.Time
+}
+
+func NewGuard(next client.Orders, markets client.Markets, capital client.Capital, limits Limits) *Guard {
+	return &Guard{
+		next:    next,
+		markets: markets,
+		capital: capital,
+		meta:    client.NewOrderValidator(markets),
+		limits:  limits,
+	}
+}
+
+// SetLimits replaces limits at runtime
+func (g *Guard) SetLimits(limits Limits) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.limits = limits
+}
+
+// Check runs all rules without sending order
+func (g *Guard) Check(payload client.ExecuteOrderPayload) error {
+	g.mu.Lock()
+	limits := g.limits
+	g.mu.Unlock()
+
+	v := g.check(limits, payload, nil)
+
+	g.event(payload, v)
+
+	if v != nil {
+		return v
+	}
+
+	return nil
+}
+
+// ExecuteOrder implements client.Orders.
+func (g *Guard) ExecuteOrder(payload client.ExecuteOrderPayload) (client.Order, error) {
+	g.mu.Lock()
+	limits := g.limits
+	g.mu.Unlock()
+
+	v := g.check(limits, payload, nil)
+	if v == nil {
+		v = g.rate(payload, 1)
+	}
+
+	g.event(payload, v)
+
+	if v != nil {
+		return nil, v
+	}
+
+	return g.next.ExecuteOrder(payload)
+}
+
+// ExecuteOrders implements client.BatchOrders.
+//
+// Rejected orders are reported in results and not sent
+func (g *Guard) ExecuteOrders(payloads []client.ExecuteOrderPayload) ([]client.BatchResult, error) {
+	batch, ok := g.next.(client.BatchOrders)
+	if !ok {
+		return nil, client.ErrBatchUnsupported
+	}
+
+	g.mu.Lock()
+	limits := g.limits
+	g.mu.Unlock()
+
+	results := make([]client.BatchResult, len(payloads))
+	passed := make([]client.ExecuteOrderPayload, 0, len(payloads))
+	index := make([]int, 0, len(payloads))
+	b := &pending{open: make(map[string]int), position: make(map[string]float64)}
+
+	for i, payload := range payloads {
+		results[i].Index = i
+
+		if v := g.check(limits, payload, b); v != nil {
+			g.event(payload, v)
+			results[i].Err = v
+			continue
+		}
+
+		passed = append(passed, payload)
+		index = append(index, i)
+	}
+
+	if len(passed) == 0 {
+		return results, nil
+	}
+
+	v := g.rate(passed[0], len(passed))
+
+	for j, payload := range passed {
+		g.event(payload, v)
+
+		if v != nil {
+			results[index[j]].Err = v
+		}
+	}
+
+	if v != nil {
+		return results, nil
+	}
+
+	sent, err := batch.ExecuteOrders(passed)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range sent {
+		r.Index = index[r.Index]
+		results[r.Index] = r
+	}
+
+	return results, nil
+}
+
+// CancelOrder implements client.Orders.
+func (g *Guard) CancelOrder(payload client.CancelOrderPayload) (client.Order, error) {
+	return g.next.CancelOrder(payload)
+}
+
+// CancelOrders implements client.Orders.
+func (g *Guard) CancelOrders(payload client.CancelOrderPayload) ([]client.Order, error) {
+	return g.next.CancelOrders(payload)
+}
+
+// CancelOrdersByID implements client.BatchOrders.
+func (g *Guard) CancelOrdersByID(symbol string, orderIds []string) ([]client.BatchResult, error) {
+	batch, ok := g.next.(client.BatchOrders)
+	if !ok {
+		return nil, client.ErrBatchUnsupported
+	}
+
+	return batch.CancelOrdersByID(symbol, orderIds)
+}
+
+// OpenOrder implements client.Orders.
+func (g *Guard) OpenOrder(clientId uint32, orderId string, symbol string) (client.Order, error) {
+	return g.next.OpenOrder(clientId, orderId, symbol)
+}
+
+// OpenOrders implements client.Orders.
+func (g *Guard) OpenOrders(symbol string) ([]client.Order, error) {
+	return g.next.OpenOrders(symbol)
+}
+
+// pending counts orders passed earlier in same batch against open orders and position limits
+type pending struct {
+	open     map[string]int
+	position map[string]float64
+}
+
+// check runs rules except rate, passed order is added to pending if it is not nil
+func (g *Guard) check(limits Limits, payload client.ExecuteOrderPayload, b *pending) *Violation {
+	symbol := payload.Symbol
+
+	if len(limits.Allowlist) > 0 && !slices.Contains(limits.Allowlist, symbol) {
+		return &Violation{Rule: RuleAllowlist, Symbol: symbol, Reason: "symbol is not allowed"}
+	}
+
+	needMid := limits.MaxOrderNotional > 0 || len(limits.MaxPosition) > 0 || limits.PriceBandBps > 0
+
+	mid := 0.0
+	if needMid {
+		var err error
+		if mid, err = g.mid(symbol); err != nil {
+			// fail closed, limits can not be checked without market data
+			return &Violation{Rule: RuleMarketData, Symbol: symbol, Reason: err.Error()}
+		}
+	}
+
+	// market trigger order executes near trigger price
+	price := num.Float(payload.Price)
+	if price == 0 {
+		price = num.Float(payload.TriggerPrice)
+	}
+	if price == 0 {
+		price = mid
+	}
+
+	quantity := num.Float(payload.Quantity)
+	notional := price * quantity
+	if payload.QuoteQuantity != "" && price > 0 {
+		notional = num.Float(payload.QuoteQuantity)
+		quantity = notional / price
+	}
+
+	if limits.PriceBandBps > 0 {
+		for _, p := range []string{payload.Price, payload.TriggerPrice} {
+			if p == "" {
+				continue
+			}
+
+			band := math.Abs(num.Float(p)-mid) / mid * 10000
+			if band > limits.PriceBandBps {
+				return &Violation{Rule: RulePriceBand, Symbol: symbol, Value: band, Limit: limits.PriceBandBps, Reason: "price is too far from mid"}
+			}
+		}
+	}
+
+	if limits.MaxOrderNotional > 0 && notional > limits.MaxOrderNotional {
+		return &Violation{Rule: RuleOrderNotional, Symbol: symbol, Value: notional, Limit: limits.MaxOrderNotional, Reason: "order notional is too big"}
+	}
+
+	if limits.MaxOpenOrders > 0 {
+		open, err := g.next.OpenOrders(symbol)
+		if err != nil {
+			return &Violation{Rule: RuleOpenOrders, Symbol: symbol, Reason: err.Error()}
+		}
+
+		count := len(open) + 1
+		if b != nil {
+			count += b.open[symbol]
+		}
+
+		if count > limits.MaxOpenOrders {
+			return &Violation{Rule: RuleOpenOrders, Symbol: symbol, Value: float64(count), Limit: float64(limits.MaxOpenOrders), Reason: "too many open orders"}
+		}
+	}
+
+	if len(limits.MaxPosition) > 0 {
+		if v := g.checkPosition(limits, payload, quantity, notional, b); v != nil {
+			return v
+		}
+	}
+
+	if b != nil {
+		b.open[symbol]++
+	}
+
+	return nil
+}
+
+// checkPosition limits balance of asset received by order
+func (g *Guard) checkPosition(limits Limits, payload client.ExecuteOrderPayload, quantity, notional float64, bt *pending) *Violation {
+	market, err := g.meta.Market(payload.Symbol)
+	if err != nil {
+		return &Violation{Rule: RuleMarketData, Symbol: payload.Symbol, Reason: err.Error()}
+	}
+
+	asset, amount := market.BaseSymbol, quantity
+	if payload.Side == string(client.SideAsk) {
+		asset, amount = market.QuoteSymbol, notional
+	}
+
+	limit, ok := limits.MaxPosition[asset]
+	if !ok {
+		return nil
+	}
+
+	balances, err := g.capital.Balances()
+	if err != nil {
+		return &Violation{Rule: RulePosition, Symbol: payload.Symbol, Reason: err.Error()}
+	}
+
+	b := balances[asset]
+	position := num.Float(b.Available) + num.Float(b.Locked) + num.Float(b.Staked) + amount
+	if bt != nil {
+		position += bt.position[asset]
+	}
+
+	if position > limit {
+		return &Violation{Rule: RulePosition, Symbol: asset, Value: position, Limit: limit, Reason: "position limit exceeded"}
+	}
+
+	if bt != nil {
+		bt.position[asset] += amount
+	}
+
+	return nil
+}
+
+// rate counts n orders against rate limit
+func (g *Guard) rate(payload client.ExecuteOrderPayload, n int) *Violation {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	limits := g.limits
+	if limits.MaxOrders <= 0 || limits.RateWindow <= 0 {
+		return nil
+	}
+
+	now := time.Now()
+	cut := now.Add(-limits.RateWindow)
+
+	i := 0
+	for i < len(g.sent) && g.sent[i].Before(cut) {
+		i++
+	}
+	g.sent = g.sent[i:]
+
+	if len(g.sent)+n > limits.MaxOrders {
+		return &Violation{Rule: RuleOrderRate, Symbol: payload.Symbol, Value: float64(len(g.sent) + n), Limit: float64(limits.MaxOrders), Reason: "order rate exceeded"}
+	}
+
+	for range n {
+		g.sent = append(g.sent, now)
+	}
+
+	return nil
+}
+
+func (g *Guard) mid(symbol string) (float64, error) {
+	depth, err := g.markets.Depth(symbol)
+	if err == nil {
+		if bid, ask := depth.Best(); bid > 0 && ask > 0 {
+			return (bid + ask) / 2, nil
+		}
+	}
+
+	ticker, err := g.markets.Ticker(symbol)
+	if err != nil {
+		return 0, err
+	}
+
+	if last := num.Float(ticker.LastPrice); last > 0 {
+		return last, nil
+	}
+
+	return 0, fmt.Errorf("no price for %s", symbol)
+}
+
+func (g *Guard) event(payload client.ExecuteOrderPayload, v *Violation) {
+	e := Event{Time: time.Now(), Payload: payload, Accepted: v == nil, Violation: v}
+
+	if g.Logger != nil && v != nil {
+		g.Logger.Warn("risk rejected order",
+			"rule", v.Rule, "symbol", payload.Symbol, "side", payload.Side,
+			"price", payload.Price, "quantity", payload.Quantity,
+			"value", v.Value, "limit", v.Limit, "reason", v.Reason)
+	}
+
+	if g.OnEvent != nil {
+		g.OnEvent(e)
+	}
+}
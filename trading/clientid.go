@@ -0,0 +1,154 @@
+package trading
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	DefaultPartitionBits = 8
+	DefaultReserveBlock  = 1000
+)
+
+var ErrClientIDExhausted = errors.New("client id space of partition is exhausted")
+
+// ClientIDStore persists high water mark of allocated client ids
+type ClientIDStore interface {
+	Load() (uint32, error)
+	Save(next uint32) error
+}
+
+// ClientIDAllocator hands out unique monotonic client ids.
+// High bits of id hold partition (bot instance), low bits hold counter.
+// Counter is reserved in blocks, so restart skips unused ids but never repeats one
+type ClientIDAllocator struct {
+	partition     uint32
+	partitionBits int
+	block         uint32
+	store         ClientIDStore
+
+	mu       sync.Mutex
+	next     uint32
+	reserved uint32
+}
+
+func NewClientIDAllocator(partition uint32, partitionBits int, store ClientIDStore) (*ClientIDAllocator, error) {
+	if partitionBits < 0 || partitionBits > 16 {
+		return nil, fmt.Errorf("partition bits must be 0-16, got %d", partitionBits)
+	}
+
+	if partition >= 1<<partitionBits {
+		return nil, fmt.Errorf("partition %d does not fit in %d bits", partition, partitionBits)
+	}
+
+	a := &ClientIDAllocator{
+		partition:     partition,
+		partitionBits: partitionBits,
+		block:         DefaultReserveBlock,
+		store:         store,
+	}
+
+	next, err := store.Load()
+	if err != nil {
+		return nil, fmt.Errorf("load client id err: %v", err)
+	}
+
+	// zero means no client id in payload
+	a.next = max(next, 1)
+	a.reserved = a.next
+
+	return a, nil
+}
+
+func (a *ClientIDAllocator) counterBits() int {
+	return 32 - a.partitionBits
+}
+
+// Next returns new client id
+func (a *ClientIDAllocator) Next() (uint32, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	limit := uint64(1) << a.counterBits()
+
+	if uint64(a.next) >= limit {
+		return 0, ErrClientIDExhausted
+	}
+
+	if a.next >= a.reserved {
+		reserved := uint32(min(uint64(a.next)+uint64(a.block), limit))
+
+		if err := a.store.Save(reserved); err != nil {
+			return 0, fmt.Errorf("save client id err: %v", err)
+		}
+
+		a.reserved = reserved
+	}
+
+	id := a.partition<<a.counterBits() | a.next
+	a.next++
+
+	return id, nil
+}
+
+// Partition of id allocated by any allocator with same partition bits
+func (a *ClientIDAllocator) Partition(id uint32) uint32 {
+	return id >> a.counterBits()
+}
+
+// Owns reports whether id was allocated by this partition
+func (a *ClientIDAllocator) Owns(id uint32) bool {
+	return id != 0 && a.Partition(id) == a.partition
+}
+
+// FileClientIDStore keeps counter in text file, write is atomic via rename
+type FileClientIDStore struct {
+	Path string
+}
+
+func (s FileClientIDStore) Load() (uint32, error) {
+	data, err := os.ReadFile(s.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	v, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 32)
+	if err != nil {
+		return 0, err
+	}
+
+	return uint32(v), nil
+}
+
+func (s FileClientIDStore) Save(next uint32) error {
+	return writeFileAtomic(s.Path, []byte(strconv.FormatUint(uint64(next), 10)))
+}
+
+// MemoryClientIDStore does not survive restarts, use for tests and paper trading
+type MemoryClientIDStore struct {
+	mu   sync.Mutex
+	next uint32
+}
+
+func (s *MemoryClientIDStore) Load() (uint32, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.next, nil
+}
+
+func (s *MemoryClientIDStore) Save(next uint32) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.next = next
+	return nil
+}
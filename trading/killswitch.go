@@ -0,0 +1,275 @@
+package trading
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/leenzstra/backpack-go/client"
+	"github.com/leenzstra/backpack-go/internal/num"
+)
+
+const (
+	DefaultKillConcurrency  = 8
+	DefaultKillVerifyRounds = 3
+)
+
+type KillReport struct {
+	// Cancelled orders per symbol
+	Cancelled map[string]int
+	// Orders still open after all verify rounds
+	Remaining map[string][]string
+	// Market orders closing base balances
+	Closed []client.Order
+	Errors []error
+}
+
+// KillSwitch cancels all open orders on every market and
+// optionally sells base balances back to CloseTo asset
+type KillSwitch struct {
+	Concurrency  int
+	VerifyRounds int
+	// Quote asset to close balances to, empty disables closing
+	CloseTo string
+	// Used to round close quantity, optional
+	Validator *client.OrderValidator
+
+	markets client.Markets
+	orders  client.Orders
+	capital client.Capital
+}
+
+func NewKillSwitch(markets client.Markets, orders client.Orders, capital client.Capital) *KillSwitch {
+	return &KillSwitch{
+		Concurrency:  DefaultKillConcurrency,
+		VerifyRounds: DefaultKillVerifyRounds,
+		markets:      markets,
+		orders:       orders,
+		capital:      capital,
+	}
+}
+
+// Trigger flattens account. Returned error joins all errors from report
+func (k *KillSwitch) Trigger(ctx context.Context) (KillReport, error) {
+	report := KillReport{
+		Cancelled: make(map[string]int),
+		Remaining: make(map[string][]string),
+	}
+
+	markets, err := k.markets.Markets()
+	if err != nil {
+		return report, fmt.Errorf("kill switch markets err: %v", err)
+	}
+
+	symbols := make([]string, 0, len(markets))
+	for _, m := range markets {
+		symbols = append(symbols, m.Symbol)
+	}
+
+	mu := sync.Mutex{}
+	// errors of last round per symbol, symbols verified clean drop them
+	errs := make(map[string][]error)
+
+	for round := 0; round < k.VerifyRounds && len(symbols) > 0; round++ {
+		clear(errs)
+
+		k.each(ctx, symbols, func(symbol string) {
+			cancelled, err := k.orders.CancelOrders(client.CancelOrderPayload{Symbol: symbol})
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				errs[symbol] = append(errs[symbol], fmt.Errorf("cancel %s err: %v", symbol, err))
+				return
+			}
+
+			report.Cancelled[symbol] += len(cancelled)
+		})
+
+		remaining := make([]string, 0)
+		clear(report.Remaining)
+
+		k.each(ctx, symbols, func(symbol string) {
+			open, err := k.orders.OpenOrders(symbol)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				errs[symbol] = append(errs[symbol], fmt.Errorf("verify %s err: %v", symbol, err))
+				remaining = append(remaining, symbol)
+				return
+			}
+
+			if len(open) == 0 {
+				delete(errs, symbol)
+				return
+			}
+
+			remaining = append(remaining, symbol)
+			for _, o := range open {
+				report.Remaining[symbol] = append(report.Remaining[symbol], o.Base().ID)
+			}
+		})
+
+		symbols = remaining
+	}
+
+	for _, symbol := range sortedKeys(errs) {
+		report.Errors = append(report.Errors, errs[symbol]...)
+	}
+
+	if ctx.Err() != nil {
+		report.Errors = append(report.Errors, ctx.Err())
+	}
+
+	if k.CloseTo != "" && ctx.Err() == nil {
+		k.close(markets, &report)
+	}
+
+	for symbol, ids := range report.Remaining {
+		report.Errors = append(report.Errors, fmt.Errorf("%s has %d open orders after kill", symbol, len(ids)))
+	}
+
+	return report, errors.Join(report.Errors...)
+}
+
+// close sells available base balances with market orders
+func (k *KillSwitch) close(markets []client.Market, report *KillReport) {
+	balances, err := k.capital.Balances()
+	if err != nil {
+		report.Errors = append(report.Errors, fmt.Errorf("close balances err: %v", err))
+		return
+	}
+
+	for _, m := range markets {
+		if m.QuoteSymbol != k.CloseTo {
+			continue
+		}
+
+		balance, ok := balances[m.BaseSymbol]
+		if !ok || num.Float(balance.Available) <= 0 {
+			continue
+		}
+
+		quantity := balance.Available
+		if k.Validator != nil {
+			if quantity, err = k.Validator.RoundQuantity(m.Symbol, quantity); err != nil {
+				report.Errors = append(report.Errors, fmt.Errorf("close %s err: %v", m.Symbol, err))
+				continue
+			}
+		}
+
+		if num.Float(quantity) <= 0 {
+			continue
+		}
+
+		order, err := k.orders.ExecuteOrder(client.ExecuteOrderPayload{
+			OrderType: string(client.OrderTypeMarket),
+			Quantity:  quantity,
+			Side:      string(client.SideAsk),
+			Symbol:    m.Symbol,
+		})
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Errorf("close %s err: %v", m.Symbol, err))
+			continue
+		}
+
+		report.Closed = append(report.Closed, order)
+	}
+}
+
+func (k *KillSwitch) each(ctx context.Context, symbols []string, fn func(symbol string)) {
+	sem := make(chan struct{}, max(k.Concurrency, 1))
+	wg := sync.WaitGroup{}
+
+	for _, symbol := range symbols {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		case sem <- struct{}{}:
+		}
+
+		wg.Add(1)
+
+		go func(symbol string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			fn(symbol)
+		}(symbol)
+	}
+
+	wg.Wait()
+}
+
+// DeadMansSwitch triggers KillSwitch when Heartbeat is not called within Timeout
+type DeadMansSwitch struct {
+	Timeout time.Duration
+	// Called once after kill switch is triggered
+	OnTrigger func(report KillReport, err error)
+
+	kill *KillSwitch
+	last atomic.Int64
+}
+
+func NewDeadMansSwitch(kill *KillSwitch, timeout time.Duration) *DeadMansSwitch {
+	d := &DeadMansSwitch{
+		Timeout: timeout,
+		kill:    kill,
+	}
+
+	d.Heartbeat()
+
+	return d
+}
+
+func (d *DeadMansSwitch) Heartbeat() {
+	d.last.Store(time.Now().UnixNano())
+}
+
+// Run watches heartbeats until ctx is done or switch is triggered
+func (d *DeadMansSwitch) Run(ctx context.Context) error {
+	d.Heartbeat()
+
+	ticker := time.NewTicker(max(d.Timeout/10, 10*time.Millisecond))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		if time.Since(time.Unix(0, d.last.Load())) < d.Timeout {
+			continue
+		}
+
+		// strategy is gone, kill must not be interrupted by its context
+		report, err := d.kill.Trigger(context.Background())
+
+		if d.OnTrigger != nil {
+			d.OnTrigger(report, err)
+		}
+
+		return err
+	}
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,583 @@
+package trading
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/leenzstra/backpack-go/client"
+	"github.com/leenzstra/backpack-go/internal/num"
+)
+
+var ErrUnknownGroup = errors.New("unknown order group")
+
+type LegStatus string
+
+const (
+	LegPending   LegStatus = "Pending"
+	LegOpen      LegStatus = "Open"
+	LegFilled    LegStatus = "Filled"
+	LegCancelled LegStatus = "Cancelled"
+)
+
+type OCOLeg struct {
+	Payload client.ExecuteOrderPayload `json:"payload"`
+	OrderID string                     `json:"orderId"`
+	Status  LegStatus                  `json:"status"`
+	// Executed on orders of this leg replaced by resize
+	PrevFilled string `json:"prevFilled"`
+	// Executed on current order
+	Executed string `json:"executed"`
+}
+
+// Total executed quantity of leg
+func (l *OCOLeg) Filled() string {
+	filled, _ := num.Add(l.PrevFilled, l.Executed)
+	return filled
+}
+
+// OCOGroup is pair of legs where fill of one leg cancels or shrinks other.
+// Bracket group has entry, legs are placed after entry is closed
+type OCOGroup struct {
+	ID     string `json:"id"`
+	Symbol string `json:"symbol"`
+	// Size covered by legs, for bracket set from entry fill
+	Quantity string     `json:"quantity"`
+	Entry    *OCOLeg    `json:"entry,omitempty"`
+	Legs     [2]*OCOLeg `json:"legs"`
+	Done     bool       `json:"done"`
+}
+
+func (g *OCOGroup) clone() OCOGroup {
+	c := *g
+
+	if g.Entry != nil {
+		entry := *g.Entry
+		c.Entry = &entry
+	}
+
+	for i, leg := range g.Legs {
+		l := *leg
+		c.Legs[i] = &l
+	}
+
+	return c
+}
+
+// OCOEngine emulates one-cancels-other and bracket orders on client side.
+// Order updates come from Tracker, state is saved to store after every change
+type OCOEngine struct {
+	// Called on failed exchange calls during updates
+	OnError func(err error)
+	// Called when group is closed, must not call engine methods
+	OnDone func(g OCOGroup)
+
+	orders  client.Orders
+	history client.History
+	tracker *Tracker
+	store   Store[[]OCOGroup]
+
+	mu      sync.Mutex
+	started bool
+	// Open groups only, closed ones are dropped after OnDone
+	groups  map[string]*OCOGroup
+	byOrder map[string]string
+	seq     int
+}
+
+func NewOCOEngine(orders client.Orders, history client.History, tracker *Tracker, store Store[[]OCOGroup]) *OCOEngine {
+	return &OCOEngine{
+		orders:  orders,
+		history: history,
+		tracker: tracker,
+		store:   store,
+		groups:  make(map[string]*OCOGroup),
+		byOrder: make(map[string]string),
+	}
+}
+
+// Start loads saved groups, reconciles them with exchange and subscribes to tracker.
+// Later calls only reconcile open groups, e.g. after reconnect
+func (e *OCOEngine) Start() error {
+	e.mu.Lock()
+
+	started := e.started
+
+	if !started {
+		groups, err := e.store.Load()
+		if err != nil {
+			e.mu.Unlock()
+			return fmt.Errorf("load oco groups err: %v", err)
+		}
+
+		for i := range groups {
+			g := &groups[i]
+			if g.Done {
+				continue
+			}
+
+			e.groups[g.ID] = g
+			e.index(g)
+		}
+
+		e.started = true
+	}
+
+	track := make([]client.Order, 0)
+
+	for _, g := range e.groups {
+		track = append(track, e.reconcile(g)...)
+	}
+
+	err := e.save()
+
+	e.mu.Unlock()
+
+	if !started {
+		e.tracker.Subscribe(e.onUpdate)
+	}
+
+	e.track(track)
+
+	return err
+}
+
+// PlaceOCO submits both legs, legs must have same quantity
+func (e *OCOEngine) PlaceOCO(first, second client.ExecuteOrderPayload) (OCOGroup, error) {
+	if cmp, err := num.Cmp(first.Quantity, second.Quantity); first.Symbol != second.Symbol || err != nil || cmp != 0 {
+		return OCOGroup{}, fmt.Errorf("%w: oco legs must have same symbol and quantity", client.ErrInvalidOrder)
+	}
+
+	e.mu.Lock()
+
+	g := e.newGroup(first.Symbol)
+	g.Quantity = first.Quantity
+	g.Legs = [2]*OCOLeg{{Payload: first, Status: LegPending}, {Payload: second, Status: LegPending}}
+
+	track := e.placeLegs(g)
+
+	var err error
+	if g.Legs[0].Status != LegOpen || g.Legs[1].Status != LegOpen {
+		// one sided oco is not protection, roll back
+		e.cancelLeg(g, g.Legs[0])
+		e.cancelLeg(g, g.Legs[1])
+		e.finish(g)
+		err = fmt.Errorf("oco %s legs were not placed", g.ID)
+	}
+
+	result := g.clone()
+	e.saveOrReport()
+
+	e.mu.Unlock()
+
+	e.track(track)
+
+	return result, err
+}
+
+// PlaceBracket submits entry, take profit and stop loss are placed for entry fill size
+func (e *OCOEngine) PlaceBracket(entry, takeProfit, stopLoss client.ExecuteOrderPayload) (OCOGroup, error) {
+	order, err := e.orders.ExecuteOrder(entry)
+	if err != nil {
+		return OCOGroup{}, err
+	}
+
+	e.mu.Lock()
+
+	g := e.newGroup(entry.Symbol)
+	g.Entry = &OCOLeg{Payload: entry, OrderID: order.Base().ID, Status: LegOpen}
+	g.Legs = [2]*OCOLeg{{Payload: takeProfit, Status: LegPending}, {Payload: stopLoss, Status: LegPending}}
+	e.byOrder[g.Entry.OrderID] = g.ID
+
+	base := order.Base()
+	track := append([]client.Order{order}, e.apply(g, base.ID, base.Status, base.ExecutedQuantity)...)
+
+	result := g.clone()
+	e.saveOrReport()
+
+	e.mu.Unlock()
+
+	e.track(track)
+
+	return result, nil
+}
+
+// Cancel closes all open orders of group
+func (e *OCOEngine) Cancel(groupId string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	g, ok := e.groups[groupId]
+	if !ok {
+		return ErrUnknownGroup
+	}
+
+	if g.Entry != nil {
+		e.cancelLeg(g, g.Entry)
+	}
+
+	e.cancelLeg(g, g.Legs[0])
+	e.cancelLeg(g, g.Legs[1])
+	e.finish(g)
+
+	return e.save()
+}
+
+// Retry repeats failed cancels of siblings of closed legs, e.g. on timer
+func (e *OCOEngine) Retry() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, g := range e.groups {
+		e.settle(g)
+	}
+
+	return e.save()
+}
+
+// Groups returns snapshot of open groups sorted by id
+func (e *OCOEngine) Groups() []OCOGroup {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	groups := make([]OCOGroup, 0, len(e.groups))
+	for _, g := range e.groups {
+		groups = append(groups, g.clone())
+	}
+
+	sort.Slice(groups, func(i, j int) bool { return groups[i].ID < groups[j].ID })
+
+	return groups
+}
+
+func (e *OCOEngine) onUpdate(o TrackedOrder) {
+	e.mu.Lock()
+
+	id, ok := e.byOrder[o.ID]
+	if !ok {
+		e.mu.Unlock()
+		return
+	}
+
+	g := e.groups[id]
+	track := e.apply(g, o.ID, o.Status, num.String(o.ExecutedQuantity))
+	e.saveOrReport()
+
+	e.mu.Unlock()
+
+	e.track(track)
+}
+
+// apply handles new state of group order, returns placed orders to track
+func (e *OCOEngine) apply(g *OCOGroup, orderId, status, executed string) []client.Order {
+	if g.Done {
+		return nil
+	}
+
+	if g.Entry != nil && g.Entry.OrderID == orderId && g.Entry.Status == LegOpen {
+		return e.applyEntry(g, status, executed)
+	}
+
+	for i, leg := range g.Legs {
+		if leg.OrderID == orderId && leg.Status == LegOpen {
+			return e.applyLeg(g, i, status, executed)
+		}
+	}
+
+	return nil
+}
+
+func (e *OCOEngine) applyEntry(g *OCOGroup, status, executed string) []client.Order {
+	if status == client.StatusFilled && num.Float(executed) <= 0 {
+		// history may miss execution, filled entry is never left unprotected
+		executed = g.Entry.Payload.Quantity
+
+		if num.Float(executed) <= 0 {
+			filled, _, err := Executed(e.history, g.Entry.OrderID, g.Symbol)
+			if err != nil || filled <= 0 {
+				e.error(fmt.Errorf("oco %s entry %s filled size is unknown, err: %v", g.ID, g.Entry.OrderID, err))
+				return nil
+			}
+
+			executed = num.String(filled)
+		}
+	}
+
+	g.Entry.Executed = executed
+
+	switch status {
+	case client.StatusFilled:
+		g.Entry.Status = LegFilled
+	case client.StatusCancelled, client.StatusExpired:
+		g.Entry.Status = LegCancelled
+	default:
+		return nil
+	}
+
+	if num.Float(executed) <= 0 {
+		e.finish(g)
+		return nil
+	}
+
+	g.Quantity = executed
+
+	return e.placeLegs(g)
+}
+
+func (e *OCOEngine) applyLeg(g *OCOGroup, i int, status, executed string) []client.Order {
+	leg, sibling := g.Legs[i], g.Legs[1-i]
+	leg.Executed = executed
+
+	switch {
+	case status == client.StatusFilled || num.Float(e.exposure(g)) <= 0:
+		leg.Status = LegFilled
+		e.settle(g)
+
+	case status == client.StatusCancelled || status == client.StatusExpired:
+		// leg was cancelled outside of engine, group is not protected anymore
+		leg.Status = LegCancelled
+		e.settle(g)
+
+	default:
+		return e.resize(g, sibling)
+	}
+
+	return nil
+}
+
+// settle cancels sibling of closed leg, group stays open until cancel succeeds
+func (e *OCOEngine) settle(g *OCOGroup) {
+	for i, leg := range g.Legs {
+		if leg.Status != LegFilled && leg.Status != LegCancelled {
+			continue
+		}
+
+		sibling := g.Legs[1-i]
+
+		e.cancelLeg(g, sibling)
+		if sibling.Status == LegOpen {
+			return
+		}
+
+		e.finish(g)
+		return
+	}
+}
+
+// resize replaces open leg if its remaining size differs from group exposure
+func (e *OCOEngine) resize(g *OCOGroup, leg *OCOLeg) []client.Order {
+	if leg.Status != LegOpen {
+		return nil
+	}
+
+	remaining, _ := num.Sub(leg.Payload.Quantity, leg.Executed)
+	if num.Float(remaining) == num.Float(e.exposure(g)) {
+		return nil
+	}
+
+	e.cancelLeg(g, leg)
+	if leg.Status != LegCancelled {
+		return nil
+	}
+
+	leg.PrevFilled = leg.Filled()
+	leg.Executed = ""
+	leg.OrderID = ""
+	leg.Status = LegPending
+
+	return e.placeLegs(g)
+}
+
+// Size not closed by any leg yet
+func (e *OCOEngine) exposure(g *OCOGroup) string {
+	exposure, _ := num.Sub(g.Quantity, g.Legs[0].Filled())
+	exposure, _ = num.Sub(exposure, g.Legs[1].Filled())
+
+	return exposure
+}
+
+func (e *OCOEngine) placeLegs(g *OCOGroup) []client.Order {
+	placed := make([]client.Order, 0, 2)
+	exposure := e.exposure(g)
+
+	for _, leg := range g.Legs {
+		if leg.Status != LegPending {
+			continue
+		}
+
+		leg.Payload.Quantity = exposure
+
+		order, err := e.orders.ExecuteOrder(leg.Payload)
+		if err != nil {
+			e.error(fmt.Errorf("oco %s place leg err: %v", g.ID, err))
+			continue
+		}
+
+		leg.OrderID = order.Base().ID
+		leg.Status = LegOpen
+		e.byOrder[leg.OrderID] = g.ID
+
+		placed = append(placed, order)
+	}
+
+	return placed
+}
+
+func (e *OCOEngine) cancelLeg(g *OCOGroup, leg *OCOLeg) {
+	if leg.Status == LegPending {
+		leg.Status = LegCancelled
+		return
+	}
+
+	if leg.Status != LegOpen {
+		return
+	}
+
+	order, err := e.orders.CancelOrder(client.CancelOrderPayload{OrderID: leg.OrderID, Symbol: g.Symbol})
+	if err != nil {
+		e.error(fmt.Errorf("oco %s cancel leg %s err: %v", g.ID, leg.OrderID, err))
+		return
+	}
+
+	leg.Status = LegCancelled
+	leg.Executed = order.Base().ExecutedQuantity
+}
+
+// reconcile applies exchange state of group orders after restart
+func (e *OCOEngine) reconcile(g *OCOGroup) []client.Order {
+	track := make([]client.Order, 0)
+
+	refresh := func(leg *OCOLeg) {
+		if leg == nil || leg.Status != LegOpen {
+			return
+		}
+
+		order, err := e.lookup(g.Symbol, leg.OrderID)
+		if err != nil {
+			e.error(fmt.Errorf("oco %s reconcile %s err: %v", g.ID, leg.OrderID, err))
+			return
+		}
+
+		base := order.Base()
+		track = append(track, e.apply(g, base.ID, base.Status, base.ExecutedQuantity)...)
+
+		if !base.IsTerminal() {
+			track = append(track, order)
+		}
+	}
+
+	refresh(g.Entry)
+	refresh(g.Legs[0])
+	refresh(g.Legs[1])
+
+	// sibling cancel failed before restart
+	e.settle(g)
+
+	// crashed between entry fill and legs placement
+	if !g.Done && (g.Entry == nil || g.Entry.Status == LegFilled) {
+		track = append(track, e.placeLegs(g)...)
+	}
+
+	return track
+}
+
+func (e *OCOEngine) lookup(symbol, orderId string) (client.Order, error) {
+	order, err := e.orders.OpenOrder(0, orderId, symbol)
+	if err == nil {
+		return order, nil
+	}
+
+	apiErr := &client.APIError{}
+	if !errors.As(err, &apiErr) || !apiErr.Rejected() {
+		return nil, err
+	}
+
+	history, err := e.history.OrderHistory(orderId, symbol, 0, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, order := range history {
+		if order.Base().ID == orderId {
+			return order, completeExecution(e.history, order)
+		}
+	}
+
+	return nil, fmt.Errorf("order %s not found", orderId)
+}
+
+func (e *OCOEngine) newGroup(symbol string) *OCOGroup {
+	e.seq++
+
+	g := &OCOGroup{
+		ID:     fmt.Sprintf("oco-%d-%d", time.Now().UnixNano(), e.seq),
+		Symbol: symbol,
+	}
+
+	e.groups[g.ID] = g
+
+	return g
+}
+
+func (e *OCOEngine) finish(g *OCOGroup) {
+	if g.Done {
+		return
+	}
+
+	g.Done = true
+
+	if e.OnDone != nil {
+		e.OnDone(g.clone())
+	}
+
+	delete(e.groups, g.ID)
+
+	for id, groupId := range e.byOrder {
+		if groupId == g.ID {
+			delete(e.byOrder, id)
+		}
+	}
+}
+
+func (e *OCOEngine) index(g *OCOGroup) {
+	if g.Entry != nil && g.Entry.OrderID != "" {
+		e.byOrder[g.Entry.OrderID] = g.ID
+	}
+
+	for _, leg := range g.Legs {
+		if leg != nil && leg.OrderID != "" {
+			e.byOrder[leg.OrderID] = g.ID
+		}
+	}
+}
+
+func (e *OCOEngine) track(orders []client.Order) {
+	for _, order := range orders {
+		e.tracker.Track(order)
+	}
+}
+
+func (e *OCOEngine) save() error {
+	groups := make([]OCOGroup, 0, len(e.groups))
+	for _, g := range e.groups {
+		groups = append(groups, g.clone())
+	}
+
+	sort.Slice(groups, func(i, j int) bool { return groups[i].ID < groups[j].ID })
+
+	return e.store.Save(groups)
+}
+
+func (e *OCOEngine) saveOrReport() {
+	if err := e.save(); err != nil {
+		e.error(fmt.Errorf("save oco groups err: %v", err))
+	}
+}
+
+func (e *OCOEngine) error(err error) {
+	if e.OnError != nil {
+		e.OnError(err)
+	}
+}
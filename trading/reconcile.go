@@ -0,0 +1,359 @@
+package trading
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/leenzstra/backpack-go/client"
+	"github.com/leenzstra/backpack-go/internal/num"
+)
+
+type DiscrepancyKind string
+
+const (
+	// Open on exchange, missing locally
+	UnknownOnExchange DiscrepancyKind = "unknown_on_exchange"
+	// Open locally, not found on exchange or in history
+	MissingOnExchange DiscrepancyKind = "missing_on_exchange"
+	// Open locally, filled on exchange
+	FilledOffline DiscrepancyKind = "filled_offline"
+	// Open locally, cancelled or expired on exchange
+	ClosedOffline DiscrepancyKind = "closed_offline"
+	// Open on both sides, executed quantity differs
+	PartialFillOffline DiscrepancyKind = "partial_fill_offline"
+	// Fills since checkpoint not reflected in local executed quantity
+	MissingFills DiscrepancyKind = "missing_fills"
+)
+
+type OrphanPolicy int
+
+const (
+	// Orphan orders are only reported
+	OrphanReport OrphanPolicy = iota
+	// Orphan orders are added to local set
+	OrphanAdopt
+	// Orphan orders are cancelled
+	OrphanCancel
+)
+
+// LocalOrder is persisted view of order
+type LocalOrder struct {
+	ID               string  `json:"id"`
+	ClientID         uint32  `json:"clientId"`
+	Symbol           string  `json:"symbol"`
+	Side             string  `json:"side"`
+	Status           string  `json:"status"`
+	Quantity         float64 `json:"quantity"`
+	ExecutedQuantity float64 `json:"executedQuantity"`
+}
+
+func LocalFromTracked(o TrackedOrder) LocalOrder {
+	return LocalOrder{
+		ID:               o.ID,
+		ClientID:         o.ClientID,
+		Symbol:           o.Symbol,
+		Side:             o.Side,
+		Status:           o.Status,
+		Quantity:         o.Quantity,
+		ExecutedQuantity: o.ExecutedQuantity,
+	}
+}
+
+func localFromOrder(order client.Order) LocalOrder {
+	base := order.Base()
+
+	return LocalOrder{
+		ID:               base.ID,
+		ClientID:         base.ClientID,
+		Symbol:           base.Symbol,
+		Side:             base.Side,
+		Status:           base.Status,
+		Quantity:         num.Float(base.Quantity),
+		ExecutedQuantity: num.Float(base.ExecutedQuantity),
+	}
+}
+
+// Checkpoint is local order set and time up to which fills are applied
+type Checkpoint struct {
+	Orders []LocalOrder `json:"orders"`
+	Time   time.Time    `json:"time"`
+}
+
+type Discrepancy struct {
+	Kind DiscrepancyKind
+	// Nil for orders unknown locally
+	Local *LocalOrder
+	// Nil if order is not found on exchange
+	Remote client.Order
+	// Fills of order since checkpoint
+	Fills []client.Fill
+	// Action taken by policy, empty if only reported
+	Action string
+	Err    error
+}
+
+type ReconcileReport struct {
+	Discrepancies []Discrepancy
+	Checkpoint    Checkpoint
+}
+
+// Reconciler compares persisted order set with exchange after restart or reconnect
+type Reconciler struct {
+	// Symbols to check, empty checks all open orders
+	Symbols []string
+	Policy  OrphanPolicy
+	// Decides if orphan belongs to this bot, e.g. ClientIDAllocator.Owns.
+	// Orders not owned are ignored, nil owns all
+	Owns func(clientId uint32) bool
+	// Optional, corrected orders are pushed to tracker
+	Tracker       *Tracker
+	OnDiscrepancy func(d Discrepancy)
+
+	orders  client.Orders
+	history client.History
+	store   Store[Checkpoint]
+}
+
+func NewReconciler(orders client.Orders, history client.History, store Store[Checkpoint]) *Reconciler {
+	return &Reconciler{orders: orders, history: history, store: store}
+}
+
+// Save stores local order set, fills up to now are treated as applied
+func (r *Reconciler) Save(orders []LocalOrder) error {
+	return r.store.Save(Checkpoint{Orders: orders, Time: time.Now()})
+}
+
+// Reconcile classifies discrepancies, applies policy and saves corrected checkpoint
+func (r *Reconciler) Reconcile() (ReconcileReport, error) {
+	checkpoint, err := r.store.Load()
+	if err != nil {
+		return ReconcileReport{}, fmt.Errorf("load checkpoint err: %v", err)
+	}
+
+	now := time.Now()
+
+	remote, err := r.open()
+	if err != nil {
+		return ReconcileReport{}, err
+	}
+
+	fills, err := r.fills(checkpoint.Time)
+	if err != nil {
+		return ReconcileReport{}, err
+	}
+
+	local := make(map[string]LocalOrder, len(checkpoint.Orders))
+	for _, o := range checkpoint.Orders {
+		local[o.ID] = o
+	}
+
+	report := ReconcileReport{}
+	errs := make([]error, 0)
+	result := make([]LocalOrder, 0, len(local))
+
+	for _, l := range checkpoint.Orders {
+		if l.Status != "" && isTerminal(l.Status) {
+			continue
+		}
+
+		if !r.watched(l.Symbol) {
+			result = append(result, l)
+			continue
+		}
+
+		l := l
+		d := Discrepancy{Local: &l, Fills: fills[l.ID]}
+
+		order, open := remote[l.ID]
+		if !open {
+			order, err = r.closed(l)
+			if err != nil {
+				errs = append(errs, err)
+				result = append(result, l)
+				continue
+			}
+		}
+
+		d.Remote = order
+
+		switch {
+		case order == nil:
+			d.Kind = MissingOnExchange
+		case !open && order.Base().Status == client.StatusFilled:
+			d.Kind = FilledOffline
+		case !open:
+			d.Kind = ClosedOffline
+		case math.Abs(num.Float(order.Base().ExecutedQuantity)-l.ExecutedQuantity) > 1e-12:
+			d.Kind = PartialFillOffline
+		case len(d.Fills) > 0:
+			// local executed quantity is as of checkpoint, later fills are not in it
+			d.Kind = MissingFills
+		default:
+			result = append(result, l)
+			continue
+		}
+
+		if order != nil {
+			updated := localFromOrder(order)
+			if open {
+				result = append(result, updated)
+			}
+
+			if r.Tracker != nil {
+				r.Tracker.Update(order)
+			}
+		}
+
+		report.Discrepancies = append(report.Discrepancies, r.emit(d))
+	}
+
+	for _, id := range sortedIDs(remote) {
+		order := remote[id]
+		if _, ok := local[id]; ok {
+			continue
+		}
+
+		if r.Owns != nil && !r.Owns(order.Base().ClientID) {
+			continue
+		}
+
+		d := Discrepancy{Kind: UnknownOnExchange, Remote: order, Fills: fills[id]}
+
+		switch r.Policy {
+		case OrphanAdopt:
+			d.Action = "adopted"
+			result = append(result, localFromOrder(order))
+
+			if r.Tracker != nil {
+				r.Tracker.Track(order)
+			}
+		case OrphanCancel:
+			d.Action = "cancelled"
+
+			base := order.Base()
+			if _, err := r.orders.CancelOrder(client.CancelOrderPayload{OrderID: base.ID, Symbol: base.Symbol}); err != nil {
+				d.Action = ""
+				d.Err = fmt.Errorf("cancel orphan %s err: %v", base.ID, err)
+				errs = append(errs, d.Err)
+			}
+		}
+
+		report.Discrepancies = append(report.Discrepancies, r.emit(d))
+	}
+
+	report.Checkpoint = Checkpoint{Orders: result, Time: now}
+
+	if err := r.store.Save(report.Checkpoint); err != nil {
+		errs = append(errs, fmt.Errorf("save checkpoint err: %v", err))
+	}
+
+	return report, errors.Join(errs...)
+}
+
+func (r *Reconciler) open() (map[string]client.Order, error) {
+	symbols := r.Symbols
+	if len(symbols) == 0 {
+		symbols = []string{""}
+	}
+
+	remote := make(map[string]client.Order)
+
+	for _, symbol := range symbols {
+		orders, err := r.orders.OpenOrders(symbol)
+		if err != nil {
+			return nil, fmt.Errorf("open orders err: %v", err)
+		}
+
+		for _, order := range orders {
+			remote[order.Base().ID] = order
+		}
+	}
+
+	return remote, nil
+}
+
+// fills groups fills since checkpoint by order
+func (r *Reconciler) fills(since time.Time) (map[string][]client.Fill, error) {
+	byOrder := make(map[string][]client.Fill)
+
+	if since.IsZero() {
+		return byOrder, nil
+	}
+
+	symbols := r.Symbols
+	if len(symbols) == 0 {
+		symbols = []string{""}
+	}
+
+	for _, symbol := range symbols {
+		pager := client.NewPager(client.FillHistoryPage(r.history, "", symbol, since, time.Time{}))
+
+		for pager.Next(context.Background()) {
+			f := pager.Item()
+			byOrder[f.OrderID] = append(byOrder[f.OrderID], f)
+		}
+
+		if err := pager.Err(); err != nil {
+			return nil, fmt.Errorf("fill history err: %v", err)
+		}
+	}
+
+	return byOrder, nil
+}
+
+// closed looks up order missing from open orders in history
+func (r *Reconciler) closed(l LocalOrder) (client.Order, error) {
+	history, err := r.history.OrderHistory(l.ID, l.Symbol, 0, 1)
+	if err != nil {
+		return nil, fmt.Errorf("order history %s err: %v", l.ID, err)
+	}
+
+	for _, order := range history {
+		if order.Base().ID == l.ID {
+			return order, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (r *Reconciler) watched(symbol string) bool {
+	if len(r.Symbols) == 0 {
+		return true
+	}
+
+	for _, s := range r.Symbols {
+		if s == symbol {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *Reconciler) emit(d Discrepancy) Discrepancy {
+	if r.OnDiscrepancy != nil {
+		r.OnDiscrepancy(d)
+	}
+
+	return d
+}
+
+func isTerminal(status string) bool {
+	return status == client.StatusFilled || status == client.StatusCancelled || status == client.StatusExpired
+}
+
+func sortedIDs(orders map[string]client.Order) []string {
+	ids := make([]string, 0, len(orders))
+	for id := range orders {
+		ids = append(ids, id)
+	}
+
+	sort.Strings(ids)
+
+	return ids
+}
@@ -0,0 +1,203 @@
+package trading
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/leenzstra/backpack-go/client"
+	"github.com/leenzstra/backpack-go/internal/num"
+)
+
+const DefaultConfirmInterval = 200 * time.Millisecond
+
+type ReplaceOutcome int
+
+const (
+	// Old order cancelled, replacement submitted
+	Replaced ReplaceOutcome = iota
+	// Old order filled before it was cancelled, nothing submitted
+	FilledBeforeCancel
+	// Old order is still live, nothing submitted
+	CancelFailed
+	// Old order cancelled, replacement rejected. No order is live
+	SubmitFailed
+	// Old order closed, filled quantity could not be confirmed, nothing submitted
+	FillUnknown
+)
+
+func (o ReplaceOutcome) String() string {
+	switch o {
+	case Replaced:
+		return "replaced"
+	case FilledBeforeCancel:
+		return "filled before cancel"
+	case CancelFailed:
+		return "cancel failed"
+	case SubmitFailed:
+		return "submit failed"
+	case FillUnknown:
+		return "fill unknown"
+	}
+
+	return fmt.Sprintf("outcome(%d)", int(o))
+}
+
+// OrderRef points to order by exchange id or client id
+type OrderRef struct {
+	Symbol   string
+	OrderID  string
+	ClientID uint32
+}
+
+type ReplaceResult struct {
+	Outcome ReplaceOutcome
+	// Final state of old order
+	Cancelled client.Order
+	// Quantity filled on old order
+	Filled      string
+	Replacement client.Order
+}
+
+// Replacer amends resting limit orders by cancel and submit
+type Replacer struct {
+	ConfirmInterval time.Duration
+
+	orders  client.Orders
+	history client.History
+}
+
+func NewReplacer(orders client.Orders, history client.History) *Replacer {
+	return &Replacer{
+		ConfirmInterval: DefaultConfirmInterval,
+		orders:          orders,
+		history:         history,
+	}
+}
+
+// Replace cancels limit order and submits new one at newPrice.
+// newQuantity is total size, quantity filled on old order is subtracted from it
+func (r *Replacer) Replace(ctx context.Context, ref OrderRef, newPrice, newQuantity string) (ReplaceResult, error) {
+	result := ReplaceResult{Outcome: CancelFailed}
+
+	cancelled, cancelErr := r.orders.CancelOrder(client.CancelOrderPayload{
+		ClientID: ref.ClientID,
+		OrderID:  ref.OrderID,
+		Symbol:   ref.Symbol,
+	})
+
+	// cancel response may be lost or order may be already closed, exchange state decides
+	final, err := r.confirm(ctx, ref, cancelled)
+	if err != nil {
+		if cancelErr != nil {
+			err = fmt.Errorf("cancel err: %v, confirm err: %w", cancelErr, err)
+		}
+
+		return result, err
+	}
+
+	base := final.Base()
+	result.Cancelled = final
+	result.Filled = base.ExecutedQuantity
+
+	if base.Status == client.StatusFilled {
+		result.Outcome = FilledBeforeCancel
+		return result, nil
+	}
+
+	// state not from cancel response may miss execution, fills decide
+	if final != cancelled || base.ExecutedQuantity == "" {
+		filled, _, err := Executed(r.history, base.ID, base.Symbol)
+		if err != nil {
+			result.Outcome = FillUnknown
+			return result, err
+		}
+
+		if filled > num.Float(base.ExecutedQuantity) {
+			result.Filled = num.String(filled)
+		}
+	}
+
+	remaining, err := num.Sub(newQuantity, result.Filled)
+	if err != nil {
+		return result, err
+	}
+
+	if num.Float(remaining) <= 0 {
+		result.Outcome = FilledBeforeCancel
+		return result, nil
+	}
+
+	// client id is not reused, exchange may still index it to old order
+	payload := client.ExecuteOrderPayload{
+		OrderType:           string(client.OrderTypeLimit),
+		Price:               newPrice,
+		Quantity:            remaining,
+		SelfTradePrevention: base.SelfTradePrevention,
+		Side:                base.Side,
+		Symbol:              base.Symbol,
+		TimeInForce:         base.TimeInForce,
+	}
+
+	if limit, ok := final.(*client.LimitOrder); ok {
+		payload.PostOnly = limit.PostOnly
+	}
+
+	replacement, err := r.orders.ExecuteOrder(payload)
+	if err != nil {
+		result.Outcome = SubmitFailed
+		return result, err
+	}
+
+	result.Outcome = Replaced
+	result.Replacement = replacement
+
+	return result, nil
+}
+
+// confirm waits until order is closed and returns its final state
+func (r *Replacer) confirm(ctx context.Context, ref OrderRef, cancelled client.Order) (client.Order, error) {
+	if cancelled != nil && cancelled.Base().IsTerminal() {
+		return cancelled, nil
+	}
+
+	for {
+		open, err := r.orders.OpenOrder(ref.ClientID, ref.OrderID, ref.Symbol)
+
+		apiErr := &client.APIError{}
+
+		switch {
+		case err == nil && !open.Base().IsTerminal():
+			// still resting, cancel did not go through
+			return nil, fmt.Errorf("order %s is still %s", open.Base().ID, open.Base().Status)
+		case err == nil:
+			return open, nil
+		case errors.As(err, &apiErr) && apiErr.Rejected():
+			return r.closed(ref)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(r.ConfirmInterval):
+		}
+	}
+}
+
+// closed finds final state of order which is not open anymore
+func (r *Replacer) closed(ref OrderRef) (client.Order, error) {
+	history, err := r.history.OrderHistory(ref.OrderID, ref.Symbol, 0, DefaultHistoryDepth)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, order := range history {
+		base := order.Base()
+		if ref.OrderID != "" && base.ID == ref.OrderID || ref.OrderID == "" && base.ClientID == ref.ClientID {
+			return order, nil
+		}
+	}
+
+	return nil, fmt.Errorf("order %s/%d is neither open nor in history", ref.OrderID, ref.ClientID)
+}
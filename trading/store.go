@@ -0,0 +1,93 @@
+package trading
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Store persists engine state between restarts
+type Store[T any] interface {
+	Load() (T, error)
+	Save(state T) error
+}
+
+// MemoryStore keeps state in process only
+type MemoryStore[T any] struct {
+	mu    sync.Mutex
+	state T
+}
+
+func (s *MemoryStore[T]) Load() (T, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.state, nil
+}
+
+func (s *MemoryStore[T]) Save(state T) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.state = state
+	return nil
+}
+
+// FileStore keeps state as JSON file, missing file loads as zero value
+type FileStore[T any] struct {
+	Path string
+}
+
+func (s FileStore[T]) Load() (T, error) {
+	var state T
+
+	data, err := os.ReadFile(s.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+
+	if err != nil {
+		return state, err
+	}
+
+	err = json.Unmarshal(data, &state)
+
+	return state, err
+}
+
+func (s FileStore[T]) Save(state T) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(s.Path, data)
+}
+
+// writeFileAtomic replaces file via rename, so crash never leaves half written state
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
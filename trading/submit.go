@@ -0,0 +1,149 @@
+package trading
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/leenzstra/backpack-go/client"
+)
+
+// ErrSubmitUnknown is returned when order may have been accepted but could not be found
+var ErrSubmitUnknown = errors.New("order submit result unknown")
+
+const (
+	DefaultSubmitRetries  = 3
+	DefaultLookupDelay    = time.Second
+	DefaultLookupAttempts = 3
+	DefaultHistoryDepth   = 100
+	// Exchange clock may differ, history is searched from before first attempt
+	lookupSkew = time.Minute
+)
+
+// Submitter sends orders with client id and never sends same order twice.
+// If result of submit is unknown, order is looked up by client id before retry
+type Submitter struct {
+	Retries int
+	// First delay before lookup, it doubles on each lookup
+	LookupDelay time.Duration
+	// Lookups which must all miss order before it is resent
+	LookupAttempts int
+
+	orders  client.Orders
+	history client.History
+	ids     *ClientIDAllocator
+}
+
+func NewSubmitter(orders client.Orders, history client.History, ids *ClientIDAllocator) *Submitter {
+	return &Submitter{
+		Retries:        DefaultSubmitRetries,
+		LookupDelay:    DefaultLookupDelay,
+		LookupAttempts: DefaultLookupAttempts,
+		orders:         orders,
+		history:        history,
+		ids:            ids,
+	}
+}
+
+// SubmitOnce executes order at most once. Client id is allocated if payload has none.
+// Returns ErrSubmitUnknown if order may be live but can not be found
+func (s *Submitter) SubmitOnce(ctx context.Context, payload client.ExecuteOrderPayload) (client.Order, error) {
+	if payload.ClientID == 0 {
+		id, err := s.ids.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		payload.ClientID = id
+	}
+
+	since := time.Now().Add(-lookupSkew)
+
+	var lastErr error
+
+	for attempt := 0; attempt <= s.Retries; attempt++ {
+		order, err := s.orders.ExecuteOrder(payload)
+		if err == nil {
+			return order, nil
+		}
+
+		if !ambiguous(err) {
+			return nil, err
+		}
+
+		lastErr = err
+
+		order, err = s.confirm(ctx, payload, since)
+		if err != nil {
+			// can not prove order is absent, resend may double position
+			return nil, fmt.Errorf("%w: submit %d err: %v, lookup err: %w", ErrSubmitUnknown, payload.ClientID, lastErr, err)
+		}
+
+		if order != nil {
+			return order, nil
+		}
+	}
+
+	return nil, fmt.Errorf("submit %d failed after %d attempts: %w", payload.ClientID, s.Retries+1, lastErr)
+}
+
+// confirm looks order up with backoff, order is absent only if every lookup misses it
+func (s *Submitter) confirm(ctx context.Context, payload client.ExecuteOrderPayload, since time.Time) (client.Order, error) {
+	attempts := max(s.LookupAttempts, 1)
+	delay := s.LookupDelay
+
+	for i := 0; i < attempts; i++ {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+
+		delay *= 2
+
+		order, err := s.Lookup(ctx, payload.Symbol, payload.ClientID, since)
+		if err != nil || order != nil {
+			return order, err
+		}
+	}
+
+	return nil, nil
+}
+
+// Lookup finds order by client id in open orders and history back to since.
+// Returns nil order without error if exchange has no such order
+func (s *Submitter) Lookup(ctx context.Context, symbol string, clientId uint32, since time.Time) (client.Order, error) {
+	order, err := s.orders.OpenOrder(clientId, "", symbol)
+	if err == nil {
+		return order, nil
+	}
+
+	apiErr := &client.APIError{}
+	if !errors.As(err, &apiErr) || !apiErr.Rejected() {
+		return nil, err
+	}
+
+	history := client.NewPager(client.OrderHistoryPage(s.history, "", symbol))
+	history.Stop = client.StopBefore(since, func(order client.Order) (time.Time, error) {
+		return time.UnixMilli(int64(order.Base().CreatedAt)), nil
+	})
+
+	for history.Next(ctx) {
+		if order := history.Item(); order.Base().ClientID == clientId {
+			return order, nil
+		}
+	}
+
+	return nil, history.Err()
+}
+
+// Network errors and 5xx responses do not tell if order was accepted
+func ambiguous(err error) bool {
+	apiErr := &client.APIError{}
+	if errors.As(err, &apiErr) {
+		return !apiErr.Rejected()
+	}
+
+	return !errors.Is(err, client.ErrInvalidOrder)
+}
@@ -0,0 +1,427 @@
+package trading
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/leenzstra/backpack-go/client"
+	"github.com/leenzstra/backpack-go/internal/num"
+)
+
+var (
+	ErrNotTracked = errors.New("order is not tracked")
+	ErrTerminal   = errors.New("order closed before condition was met")
+)
+
+const (
+	DefaultMinPollInterval = 500 * time.Millisecond
+	DefaultMaxPollInterval = 10 * time.Second
+)
+
+// TrackedOrder is snapshot of order known to Tracker
+type TrackedOrder struct {
+	Order                 client.Order
+	ID                    string
+	ClientID              uint32
+	Symbol                string
+	Side                  string
+	Status                string
+	Quantity              float64
+	ExecutedQuantity      float64
+	ExecutedQuoteQuantity float64
+	UpdatedAt             time.Time
+}
+
+// Average execution price, zero if nothing is executed or executed quote is unknown
+func (o TrackedOrder) AveragePrice() float64 {
+	if o.ExecutedQuantity == 0 {
+		return 0
+	}
+
+	return o.ExecutedQuoteQuantity / o.ExecutedQuantity
+}
+
+func (o TrackedOrder) IsTerminal() bool {
+	return o.Order.Base().IsTerminal()
+}
+
+func (o TrackedOrder) Remaining() float64 {
+	return o.Quantity - o.ExecutedQuantity
+}
+
+// Condition is checked by Wait on each order update
+type Condition func(o TrackedOrder) bool
+
+func Filled(o TrackedOrder) bool {
+	return o.Status == client.StatusFilled
+}
+
+func Terminal(o TrackedOrder) bool {
+	return o.IsTerminal()
+}
+
+func AnyFill(o TrackedOrder) bool {
+	return o.ExecutedQuantity > 0
+}
+
+// Tracker follows submitted orders until they are closed.
+// Updates come from Update (e.g. private stream) or from polling in Run
+type Tracker struct {
+	MinPollInterval time.Duration
+	MaxPollInterval time.Duration
+
+	// Called when executed quantity grows
+	OnFill func(prev, cur TrackedOrder)
+	// Called on every status or quantity change
+	OnUpdate func(o TrackedOrder)
+	// Called on polling errors
+	OnError func(err error)
+
+	orders  client.Orders
+	history client.History
+
+	mu          sync.Mutex
+	tracked     map[string]*TrackedOrder
+	clientID    map[uint32]string
+	changed     chan struct{}
+	subscribers []func(o TrackedOrder)
+}
+
+func NewTracker(orders client.Orders, history client.History) *Tracker {
+	return &Tracker{
+		MinPollInterval: DefaultMinPollInterval,
+		MaxPollInterval: DefaultMaxPollInterval,
+		orders:          orders,
+		history:         history,
+		tracked:         make(map[string]*TrackedOrder),
+		clientID:        make(map[uint32]string),
+		changed:         make(chan struct{}),
+	}
+}
+
+// Submit executes order and starts tracking it
+func (t *Tracker) Submit(payload client.ExecuteOrderPayload) (TrackedOrder, error) {
+	order, err := t.orders.ExecuteOrder(payload)
+	if err != nil {
+		return TrackedOrder{}, err
+	}
+
+	return t.Track(order), nil
+}
+
+// Track registers order returned by ExecuteOrder or OpenOrder
+func (t *Tracker) Track(order client.Order) TrackedOrder {
+	t.Update(order)
+
+	o, _ := t.Get(order.Base().ID)
+	return o
+}
+
+// Subscribe adds listener called on every change after OnUpdate
+func (t *Tracker) Subscribe(fn func(o TrackedOrder)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.subscribers = append(t.subscribers, fn)
+}
+
+// Forget stops tracking order
+func (t *Tracker) Forget(id string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if o, ok := t.tracked[id]; ok {
+		delete(t.clientID, o.ClientID)
+		delete(t.tracked, id)
+	}
+}
+
+func (t *Tracker) Get(id string) (TrackedOrder, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	o, ok := t.tracked[id]
+	if !ok {
+		return TrackedOrder{}, false
+	}
+
+	return *o, true
+}
+
+func (t *Tracker) GetByClientID(clientId uint32) (TrackedOrder, bool) {
+	t.mu.Lock()
+	id, ok := t.clientID[clientId]
+	t.mu.Unlock()
+
+	if !ok {
+		return TrackedOrder{}, false
+	}
+
+	return t.Get(id)
+}
+
+// Snapshot of all tracked orders
+func (t *Tracker) Snapshot() []TrackedOrder {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	orders := make([]TrackedOrder, 0, len(t.tracked))
+	for _, o := range t.tracked {
+		orders = append(orders, *o)
+	}
+
+	return orders
+}
+
+// Update applies fresh order state. Safe to call from stream handlers
+func (t *Tracker) Update(order client.Order) {
+	base := order.Base()
+
+	t.mu.Lock()
+
+	prev, known := t.tracked[base.ID]
+	if known && prev.IsTerminal() {
+		t.mu.Unlock()
+		return
+	}
+
+	cur := &TrackedOrder{
+		Order:                 order,
+		ID:                    base.ID,
+		ClientID:              base.ClientID,
+		Symbol:                base.Symbol,
+		Side:                  base.Side,
+		Status:                base.Status,
+		Quantity:              num.Float(base.Quantity),
+		ExecutedQuantity:      num.Float(base.ExecutedQuantity),
+		ExecutedQuoteQuantity: num.Float(base.ExecutedQuoteQuantity),
+		UpdatedAt:             time.Now(),
+	}
+
+	if known {
+		// history responses may miss execution fields
+		if cur.ExecutedQuantity < prev.ExecutedQuantity {
+			cur.ExecutedQuantity = prev.ExecutedQuantity
+			cur.ExecutedQuoteQuantity = prev.ExecutedQuoteQuantity
+		}
+
+		if cur.Quantity == 0 {
+			cur.Quantity = prev.Quantity
+		}
+	}
+
+	if cur.Status == client.StatusFilled && cur.ExecutedQuantity < cur.Quantity {
+		// quote covers only part of quantity, average is unknown
+		cur.ExecutedQuantity = cur.Quantity
+		cur.ExecutedQuoteQuantity = 0
+	}
+
+	changed := !known || prev.Status != cur.Status || prev.ExecutedQuantity != cur.ExecutedQuantity
+
+	t.tracked[cur.ID] = cur
+	if cur.ClientID != 0 {
+		t.clientID[cur.ClientID] = cur.ID
+	}
+
+	if changed {
+		close(t.changed)
+		t.changed = make(chan struct{})
+	}
+
+	subscribers := t.subscribers
+
+	t.mu.Unlock()
+
+	if !changed {
+		return
+	}
+
+	if t.OnUpdate != nil {
+		t.OnUpdate(*cur)
+	}
+
+	for _, fn := range subscribers {
+		fn(*cur)
+	}
+
+	if t.OnFill != nil && (!known && cur.ExecutedQuantity > 0 || known && cur.ExecutedQuantity > prev.ExecutedQuantity) {
+		p := TrackedOrder{}
+		if known {
+			p = *prev
+		}
+
+		t.OnFill(p, *cur)
+	}
+}
+
+// Wait blocks until condition is met for order.
+// Returns ErrTerminal if order is closed without meeting condition
+func (t *Tracker) Wait(ctx context.Context, id string, condition Condition) (TrackedOrder, error) {
+	for {
+		t.mu.Lock()
+		o, ok := t.tracked[id]
+		changed := t.changed
+		var snapshot TrackedOrder
+		if ok {
+			snapshot = *o
+		}
+		t.mu.Unlock()
+
+		if !ok {
+			return TrackedOrder{}, ErrNotTracked
+		}
+
+		if condition(snapshot) {
+			return snapshot, nil
+		}
+
+		if snapshot.IsTerminal() {
+			return snapshot, fmt.Errorf("%w: %s", ErrTerminal, snapshot.Status)
+		}
+
+		select {
+		case <-ctx.Done():
+			return snapshot, ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+func (t *Tracker) WaitForFill(ctx context.Context, id string) (TrackedOrder, error) {
+	return t.Wait(ctx, id, Filled)
+}
+
+func (t *Tracker) WaitForTerminal(ctx context.Context, id string) (TrackedOrder, error) {
+	return t.Wait(ctx, id, Terminal)
+}
+
+// Run polls exchange for tracked orders until ctx is done.
+// Interval shrinks to MinPollInterval on changes and backs off to MaxPollInterval otherwise
+func (t *Tracker) Run(ctx context.Context) error {
+	interval := t.MinPollInterval
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+
+		if t.Poll() {
+			interval = t.MinPollInterval
+		} else {
+			interval = min(interval*2, t.MaxPollInterval)
+		}
+	}
+}
+
+// Poll refreshes all active orders once, returns true if any order changed
+func (t *Tracker) Poll() bool {
+	t.mu.Lock()
+	changedBefore := t.changed
+	active := make(map[string][]string)
+	for _, o := range t.tracked {
+		if !o.IsTerminal() {
+			active[o.Symbol] = append(active[o.Symbol], o.ID)
+		}
+	}
+	t.mu.Unlock()
+
+	for symbol, ids := range active {
+		open, err := t.orders.OpenOrders(symbol)
+		if err != nil {
+			t.error(fmt.Errorf("poll open orders %s err: %v", symbol, err))
+			continue
+		}
+
+		seen := make(map[string]bool, len(open))
+		for _, order := range open {
+			seen[order.Base().ID] = true
+
+			t.mu.Lock()
+			_, ok := t.tracked[order.Base().ID]
+			t.mu.Unlock()
+
+			if ok {
+				t.Update(order)
+			}
+		}
+
+		// order dropped out of open orders, final state is in history
+		for _, id := range ids {
+			if seen[id] {
+				continue
+			}
+
+			history, err := t.history.OrderHistory(id, symbol, 0, 1)
+			if err != nil {
+				t.error(fmt.Errorf("poll order history %s err: %v", id, err))
+				continue
+			}
+
+			for _, order := range history {
+				if order.Base().ID != id {
+					continue
+				}
+
+				if err := completeExecution(t.history, order); err != nil {
+					t.error(err)
+					continue
+				}
+
+				t.Update(order)
+			}
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return changedBefore != t.changed
+}
+
+// completeExecution fills execution fields missing in history response from fill history
+func completeExecution(history client.History, order client.Order) error {
+	base := order.Base()
+
+	if !base.IsTerminal() || base.ExecutedQuantity != "" && base.ExecutedQuoteQuantity != "" {
+		return nil
+	}
+
+	quantity, quote, err := Executed(history, base.ID, base.Symbol)
+	if err != nil {
+		return err
+	}
+
+	base.ExecutedQuantity = num.String(quantity)
+	base.ExecutedQuoteQuantity = num.String(quote)
+
+	return nil
+}
+
+// Executed sums fills of order, quote is sum of price times quantity
+func Executed(history client.History, orderId, symbol string) (quantity, quote float64, err error) {
+	pager := client.NewPager(client.FillHistoryPage(history, orderId, symbol, time.Time{}, time.Time{}))
+
+	for pager.Next(context.Background()) {
+		f := pager.Item()
+
+		quantity += num.Float(f.Quantity)
+		quote += num.Float(f.Quantity) * num.Float(f.Price)
+	}
+
+	if err := pager.Err(); err != nil {
+		return 0, 0, fmt.Errorf("fill history %s err: %v", orderId, err)
+	}
+
+	return quantity, quote, nil
+}
+
+func (t *Tracker) error(err error) {
+	if t.OnError != nil {
+		t.OnError(err)
+	}
+}
@@ -0,0 +1,275 @@
+package trading
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/leenzstra/backpack-go/client"
+	"github.com/leenzstra/backpack-go/internal/num"
+)
+
+const DefaultFeedInterval = time.Second
+
+// PriceFeed yields last prices, Next blocks until new price is available
+type PriceFeed interface {
+	Next(ctx context.Context) (float64, error)
+}
+
+// TickerFeed polls Ticker last price
+type TickerFeed struct {
+	Symbol   string
+	Interval time.Duration
+
+	markets client.Markets
+	started bool
+}
+
+func NewTickerFeed(markets client.Markets, symbol string) *TickerFeed {
+	return &TickerFeed{Symbol: symbol, Interval: DefaultFeedInterval, markets: markets}
+}
+
+func (f *TickerFeed) Next(ctx context.Context) (float64, error) {
+	if err := f.wait(ctx); err != nil {
+		return 0, err
+	}
+
+	ticker, err := f.markets.Ticker(f.Symbol)
+	if err != nil {
+		return 0, err
+	}
+
+	return num.Float(ticker.LastPrice), nil
+}
+
+func (f *TickerFeed) wait(ctx context.Context) error {
+	if !f.started {
+		f.started = true
+		return nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(f.Interval):
+		return nil
+	}
+}
+
+// TradeFeed polls last public trade
+type TradeFeed struct {
+	Symbol   string
+	Interval time.Duration
+
+	trades client.Trades
+	lastID int64
+}
+
+func NewTradeFeed(trades client.Trades, symbol string) *TradeFeed {
+	return &TradeFeed{Symbol: symbol, Interval: DefaultFeedInterval, trades: trades}
+}
+
+func (f *TradeFeed) Next(ctx context.Context) (float64, error) {
+	for {
+		trades, err := f.trades.RecentTrades(f.Symbol, 1)
+		if err != nil {
+			return 0, err
+		}
+
+		if len(trades) > 0 && trades[len(trades)-1].ID != f.lastID {
+			trade := trades[len(trades)-1]
+			f.lastID = trade.ID
+
+			return num.Float(trade.Price), nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-time.After(f.Interval):
+		}
+	}
+}
+
+// ChanFeed is fed by caller, e.g. from stream handler or simulation
+type ChanFeed chan float64
+
+func (f ChanFeed) Next(ctx context.Context) (float64, error) {
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	case price, ok := <-f:
+		if !ok {
+			return 0, errors.New("price feed closed")
+		}
+
+		return price, nil
+	}
+}
+
+type TrailingConfig struct {
+	Symbol string
+	// Side of exit order, Ask closes long position, Bid closes short
+	Side     client.Side
+	Quantity string
+	// Distance from best price, in percent if Percent is set
+	Distance float64
+	Percent  bool
+	// Trailing starts when price reaches activation, zero starts immediately
+	ActivationPrice float64
+	// Exit is limit order at stop price shifted by offset against trigger, zero uses market order
+	LimitOffset float64
+}
+
+type TrailingState struct {
+	Active bool
+	// Highest price for Ask exit, lowest for Bid exit since activation
+	Extreme float64
+	Stop    float64
+	Last    float64
+}
+
+// TrailingStop ratchets stop behind price and fires exit order when it is hit
+type TrailingStop struct {
+	// Called when stop moves
+	OnMove func(s TrailingState)
+	// Optional rounding of exit price and quantity
+	Validator *client.OrderValidator
+
+	cfg    TrailingConfig
+	orders client.Orders
+	feed   PriceFeed
+
+	mu    sync.Mutex
+	state TrailingState
+}
+
+func NewTrailingStop(orders client.Orders, feed PriceFeed, cfg TrailingConfig) (*TrailingStop, error) {
+	if cfg.Distance <= 0 {
+		return nil, fmt.Errorf("trailing distance must be positive")
+	}
+
+	if cfg.Percent && cfg.Distance >= 100 {
+		return nil, fmt.Errorf("trailing percent must be below 100")
+	}
+
+	if cfg.Side != client.SideAsk && cfg.Side != client.SideBid {
+		return nil, fmt.Errorf("unknown side %q", cfg.Side)
+	}
+
+	return &TrailingStop{cfg: cfg, orders: orders, feed: feed}, nil
+}
+
+func (t *TrailingStop) State() TrailingState {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.state
+}
+
+// Run follows price until stop is hit and returns exit order
+func (t *TrailingStop) Run(ctx context.Context) (client.Order, error) {
+	for {
+		price, err := t.feed.Next(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		if price <= 0 {
+			continue
+		}
+
+		if t.observe(price) {
+			return t.exit()
+		}
+	}
+}
+
+// observe applies price and reports whether stop is hit
+func (t *TrailingStop) observe(price float64) bool {
+	t.mu.Lock()
+
+	long := t.cfg.Side == client.SideAsk
+	s := &t.state
+	s.Last = price
+
+	if !s.Active {
+		activation := t.cfg.ActivationPrice
+		if activation != 0 && (long && price < activation || !long && price > activation) {
+			t.mu.Unlock()
+			return false
+		}
+
+		s.Active = true
+		s.Extreme = price
+	}
+
+	if s.Stop != 0 && (long && price <= s.Stop || !long && price >= s.Stop) {
+		t.mu.Unlock()
+		return true
+	}
+
+	moved := s.Stop == 0
+
+	if long && price > s.Extreme || !long && price < s.Extreme {
+		s.Extreme = price
+		moved = true
+	}
+
+	if moved {
+		s.Stop = t.stop(s.Extreme)
+	}
+
+	state := *s
+	t.mu.Unlock()
+
+	if moved && t.OnMove != nil {
+		t.OnMove(state)
+	}
+
+	return false
+}
+
+func (t *TrailingStop) stop(extreme float64) float64 {
+	distance := t.cfg.Distance
+	if t.cfg.Percent {
+		distance = extreme * t.cfg.Distance / 100
+	}
+
+	if t.cfg.Side == client.SideAsk {
+		return extreme - distance
+	}
+
+	return extreme + distance
+}
+
+func (t *TrailingStop) exit() (client.Order, error) {
+	payload := client.ExecuteOrderPayload{
+		OrderType: string(client.OrderTypeMarket),
+		Quantity:  t.cfg.Quantity,
+		Side:      string(t.cfg.Side),
+		Symbol:    t.cfg.Symbol,
+	}
+
+	if t.cfg.LimitOffset != 0 {
+		stop := t.State().Stop
+
+		price := stop - t.cfg.LimitOffset
+		if t.cfg.Side == client.SideBid {
+			price = stop + t.cfg.LimitOffset
+		}
+
+		payload.OrderType = string(client.OrderTypeLimit)
+		payload.Price = num.String(price)
+	}
+
+	if t.Validator != nil {
+		if err := t.Validator.Apply(&payload); err != nil {
+			return nil, err
+		}
+	}
+
+	return t.orders.ExecuteOrder(payload)
+}